@@ -0,0 +1,193 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// bilateralPad is the number of empty grid cells around each axis of the
+// bilateral grid, so that the blur can spread past the edges and slicing
+// needs no bounds checks.
+const bilateralPad = 2
+
+// The cells of the bilateral grid are at least bilateralCellSize pixels
+// wide and bilateralCellLevels levels of luminance deep, and there are at
+// most bilateralMaxCells of them. This bounds the memory the grid needs,
+// which would otherwise grow without limit for small sigmas.
+const (
+	bilateralCellSize   = 4
+	bilateralCellLevels = 8
+	bilateralMaxCells   = 1 << 21
+)
+
+// Bilateral produces a smoothed version of the image that preserves edges.
+// Each pixel is a mean of the pixels around it, weighted by a Gaussian of
+// their distance with standard deviation spatialSigma, and by a Gaussian of
+// their difference in luminance, in the range [0, 255], with standard
+// deviation rangeSigma.
+//
+// It is approximated with a bilateral grid, so its cost does not depend on
+// either sigma. The cells of the grid have a minimum size, and grow on
+// large images to bound the memory used, so sigmas smaller than a cell are
+// approximated coarsely. See J. Chen, S. Paris, F. Durand. Real-time
+// Edge-Aware Image Processing with the Bilateral Grid, ACM SIGGRAPH 2007.
+func Bilateral(dst draw.Image, src image.Image, spatialSigma, rangeSigma float64) error {
+	if dst == nil {
+		return errors.New("graphics: dst is nil")
+	}
+	if src == nil {
+		return errors.New("graphics: src is nil")
+	}
+	if spatialSigma <= 0 || rangeSigma <= 0 {
+		return errors.New("graphics: bilateral sigma is not positive")
+	}
+
+	b := dst.Bounds()
+	if b.Empty() {
+		return nil
+	}
+	w, h := b.Dx(), b.Dy()
+	buf := loadRGBA(src, b)
+
+	// Each grid cell covers ss pixels along x and y, and sr levels of
+	// luminance. A cell holds the sums of R, G, B, A and the pixel count.
+	ss := math.Max(spatialSigma, bilateralCellSize)
+	sr := math.Max(rangeSigma, bilateralCellLevels)
+	gd := int(255/sr) + 1 + 2*bilateralPad
+	if n := float64(w) * float64(h) / (ss * ss) * float64(gd); n > bilateralMaxCells {
+		ss *= math.Sqrt(n / bilateralMaxCells)
+	}
+	gw := int(float64(w-1)/ss) + 1 + 2*bilateralPad
+	gh := int(float64(h-1)/ss) + 1 + 2*bilateralPad
+	grid := make([]float64, gw*gh*gd*5)
+	cell := func(x, y, z int) int { return ((z*gh+y)*gw + x) * 5 }
+
+	// Splat each pixel into its nearest cell.
+	lum := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			p := buf[i*4 : i*4+4]
+			lum[i] = 0.299*p[0] + 0.587*p[1] + 0.114*p[2]
+			o := cell(
+				int(float64(x)/ss+0.5)+bilateralPad,
+				int(float64(y)/ss+0.5)+bilateralPad,
+				int(lum[i]/sr+0.5)+bilateralPad,
+			)
+			grid[o+0] += p[0]
+			grid[o+1] += p[1]
+			grid[o+2] += p[2]
+			grid[o+3] += p[3]
+			grid[o+4]++
+		}
+	}
+
+	// Blur the grid along each axis with a Gaussian of the sigmas, measured
+	// in cells.
+	ks := bilateralKernel(spatialSigma / ss)
+	kr := bilateralKernel(rangeSigma / sr)
+	bilateralBlur(grid, 1, gw, ks)
+	bilateralBlur(grid, gw, gh, ks)
+	bilateralBlur(grid, gw*gh, gd, kr)
+
+	// Slice the grid at each pixel, with trilinear interpolation.
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			gx := float64(x)/ss + bilateralPad
+			gy := float64(y)/ss + bilateralPad
+			gz := lum[i]/sr + bilateralPad
+			x0, y0, z0 := int(gx), int(gy), int(gz)
+			fx, fy, fz := gx-float64(x0), gy-float64(y0), gz-float64(z0)
+
+			var sum [5]float64
+			for dz := 0; dz < 2; dz++ {
+				for dy := 0; dy < 2; dy++ {
+					for dx := 0; dx < 2; dx++ {
+						f := lerpWeight(fx, dx) * lerpWeight(fy, dy) * lerpWeight(fz, dz)
+						o := cell(x0+dx, y0+dy, z0+dz)
+						for c := range sum {
+							sum[c] += grid[o+c] * f
+						}
+					}
+				}
+			}
+			if sum[4] == 0 {
+				continue
+			}
+			for c := 0; c < 4; c++ {
+				buf[i*4+c] = sum[c] / sum[4]
+			}
+		}
+	}
+
+	storeRGBA(dst, buf)
+	return nil
+}
+
+// bilateralKernel returns the weights of a Gaussian with standard deviation
+// sigma, no more than one, out to bilateralPad on either side.
+func bilateralKernel(sigma float64) []float64 {
+	k := make([]float64, 2*bilateralPad+1)
+	sum := 0.0
+	for i := range k {
+		d := float64(i - bilateralPad)
+		k[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += k[i]
+	}
+	for i := range k {
+		k[i] /= sum
+	}
+	return k
+}
+
+// bilateralBlur convolves grid in place with k along the axis whose cells
+// are step cells apart and n cells long. Only one line along the axis is
+// copied at a time.
+func bilateralBlur(grid []float64, step, n int, k []float64) {
+	line := make([]float64, n*5)
+	for base := 0; base < len(grid)/5; base += step * n {
+		for start := base; start < base+step; start++ {
+			for j := 0; j < n; j++ {
+				o := (start + j*step) * 5
+				for c := 0; c < 5; c++ {
+					line[j*5+c] = grid[o+c]
+				}
+			}
+			for j := 0; j < n; j++ {
+				var sum [5]float64
+				for i, f := range k {
+					sj := j + i - bilateralPad
+					if sj < 0 || sj >= n {
+						continue
+					}
+					p := line[sj*5 : sj*5+5]
+					sum[0] += p[0] * f
+					sum[1] += p[1] * f
+					sum[2] += p[2] * f
+					sum[3] += p[3] * f
+					sum[4] += p[4] * f
+				}
+				o := (start + j*step) * 5
+				for c := 0; c < 5; c++ {
+					grid[o+c] = sum[c]
+				}
+			}
+		}
+	}
+}
+
+// lerpWeight returns the linear interpolation weight of the lower (d = 0) or
+// upper (d = 1) neighbor, at fraction f between them.
+func lerpWeight(f float64, d int) float64 {
+	if d == 0 {
+		return 1 - f
+	}
+	return f
+}
@@ -0,0 +1,135 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"github.com/BurntSushi/graphics-go/graphics/graphicstest"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+
+	_ "image/png"
+)
+
+// bilateralExact is a brute force bilateral filter, for reference.
+func bilateralExact(dst *image.RGBA, src image.Image, spatialSigma, rangeSigma float64) {
+	b := dst.Bounds()
+	w, h := b.Dx(), b.Dy()
+	buf := loadRGBA(src, b)
+	lum := func(i int) float64 {
+		return 0.299*buf[i*4] + 0.587*buf[i*4+1] + 0.114*buf[i*4+2]
+	}
+	radius := int(math.Ceil(3 * spatialSigma))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var sum [4]float64
+			wsum := 0.0
+			l := lum(y*w + x)
+			for cy := y - radius; cy <= y+radius; cy++ {
+				for cx := x - radius; cx <= x+radius; cx++ {
+					if cx < 0 || cy < 0 || cx >= w || cy >= h {
+						continue
+					}
+					j := cy*w + cx
+					ds := float64((cx-x)*(cx-x) + (cy-y)*(cy-y))
+					dr := lum(j) - l
+					f := math.Exp(-ds/(2*spatialSigma*spatialSigma) - dr*dr/(2*rangeSigma*rangeSigma))
+					for c := range sum {
+						sum[c] += buf[j*4+c] * f
+					}
+					wsum += f
+				}
+			}
+			dst.SetRGBA(b.Min.X+x, b.Min.Y+y, color.RGBA{
+				uint8(sum[0]/wsum + 0.5),
+				uint8(sum[1]/wsum + 0.5),
+				uint8(sum[2]/wsum + 0.5),
+				uint8(sum[3]/wsum + 0.5),
+			})
+		}
+	}
+}
+
+func TestBilateralEdge(t *testing.T) {
+	b := image.Rect(0, 0, 20, 20)
+	src := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			v := uint8(0x20)
+			if x >= 10 {
+				v = 0xe0
+			}
+			// Add some noise to be smoothed away.
+			v += uint8((x*7 + y*13) % 5)
+			src.SetRGBA(x, y, color.RGBA{v, v, v, 0xff})
+		}
+	}
+
+	dst := image.NewRGBA(b)
+	if err := Bilateral(dst, src, 3, 20); err != nil {
+		t.Fatal(err)
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		if got := dst.RGBAAt(9, y).R; got < 0x20 || got > 0x25 {
+			t.Errorf("(9, %d): got %#x want about 0x22", y, got)
+		}
+		if got := dst.RGBAAt(10, y).R; got < 0xe0 || got > 0xe5 {
+			t.Errorf("(10, %d): got %#x want about 0xe2", y, got)
+		}
+	}
+}
+
+func TestBilateralGopher(t *testing.T) {
+	src, err := graphicstest.LoadImage("../testdata/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Filter part of the gopher, moved to the origin.
+	b := image.Rect(0, 0, 100, 100)
+	eye := image.NewRGBA(b)
+	draw.Draw(eye, b, src, image.Pt(150, 200), draw.Src)
+	src = eye
+
+	dst := image.NewRGBA(b)
+	if err := Bilateral(dst, src, 3, 30); err != nil {
+		t.Fatal(err)
+	}
+	cmp := image.NewRGBA(b)
+	bilateralExact(cmp, src, 3, 30)
+
+	// The grid is an approximation, so compare the smoothed results.
+	got := image.NewRGBA(b)
+	want := image.NewRGBA(b)
+	if err := Blur(got, dst, &BlurOptions{StdDev: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Blur(want, cmp, &BlurOptions{StdDev: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := graphicstest.ImageWithinTolerance(got, want, 0x1800); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBilateralInvalid(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	if err := Bilateral(m, m, 0, 1); err == nil {
+		t.Error("zero sigma: got nil error")
+	}
+	if err := Bilateral(nil, m, 1, 1); err == nil {
+		t.Error("nil dst: got nil error")
+	}
+}
+
+func BenchmarkBilateral3000x2000x1(b *testing.B) {
+	src := noisyRGBA(image.Rect(0, 0, 3000, 2000))
+	dst := image.NewRGBA(src.Bounds())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Bilateral(dst, src, 1, 1)
+	}
+}
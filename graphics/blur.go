@@ -7,6 +7,7 @@ package graphics
 import (
 	"github.com/BurntSushi/graphics-go/graphics/convolve"
 	"errors"
+	"fmt"
 	"image"
 	"image/draw"
 	"math"
@@ -15,12 +16,35 @@ import (
 // DefaultStdDev is the default blurring parameter.
 var DefaultStdDev = 0.5
 
+// BlurMethod is an algorithm used to blur an image.
+type BlurMethod int
+
+const (
+	// BlurKernel convolves with a Gaussian kernel. Its cost grows linearly
+	// with the kernel size.
+	BlurKernel BlurMethod = iota
+	// BlurBox approximates a Gaussian with three successive box blurs. Its
+	// cost does not depend on StdDev, and Size is ignored.
+	BlurBox
+	// BlurRecursive uses a recursive (IIR) Gaussian filter. Its cost does not
+	// depend on StdDev, and Size is ignored. Away from the image borders,
+	// pixel values are within 1/255 of BlurKernel. A StdDev below 0.5 falls
+	// back to BlurKernel.
+	BlurRecursive
+)
+
 // BlurOptions are the blurring parameters.
 // StdDev is the standard deviation of the normal, higher is blurrier.
+// StdDevX and StdDevY, if non-zero, replace StdDev along the horizontal and
+// vertical axes, for an anisotropic blur.
 // Size is the size of the kernel. If zero, it is set to Ceil(6 * StdDev).
+// Method is the blurring algorithm, BlurKernel by default.
 type BlurOptions struct {
-	StdDev float64
-	Size   int
+	StdDev  float64
+	StdDevX float64
+	StdDevY float64
+	Size    int
+	Method  BlurMethod
 }
 
 // Blur produces a blurred version of the image, using a Gaussian blur.
@@ -32,14 +56,48 @@ func Blur(dst draw.Image, src image.Image, opt *BlurOptions) error {
 		return errors.New("graphics: src is nil")
 	}
 
-	sd := DefaultStdDev
+	sdx, sdy := DefaultStdDev, DefaultStdDev
 	size := 0
+	method := BlurKernel
 
 	if opt != nil {
-		sd = opt.StdDev
+		sdx, sdy = opt.StdDev, opt.StdDev
+		if opt.StdDevX != 0 {
+			sdx = opt.StdDevX
+		}
+		if opt.StdDevY != 0 {
+			sdy = opt.StdDevY
+		}
 		size = opt.Size
+		method = opt.Method
 	}
 
+	switch method {
+	case BlurKernel:
+	case BlurBox:
+		return boxBlur(dst, src, boxRadii(sdx, 3), boxRadii(sdy, 3))
+	case BlurRecursive:
+		if sdx >= 0.5 && sdy >= 0.5 {
+			return recursiveBlur(dst, src, sdx, sdy)
+		}
+	default:
+		return fmt.Errorf("graphics: unknown blur method %d", method)
+	}
+
+	return convolve.Convolve(dst, src, &convolve.SeparableKernel{
+		X: gaussianKernel(sdx, size),
+		Y: gaussianKernel(sdy, size),
+	})
+}
+
+// gaussianKernel returns the weights of a one dimensional Gaussian with
+// standard deviation sd, reaching size pixels either side of the center.
+// If size is zero, it is set to Ceil(6 * sd). A non-positive sd gives
+// the identity.
+func gaussianKernel(sd float64, size int) []float64 {
+	if sd <= 0 {
+		return []float64{1}
+	}
 	if size < 1 {
 		size = int(math.Ceil(sd * 6))
 	}
@@ -60,9 +118,5 @@ func Blur(dst draw.Image, src image.Image, opt *BlurOptions) error {
 	for i, k := range kernel {
 		kernel[i] = k / kSum
 	}
-
-	return convolve.Convolve(dst, src, &convolve.SeparableKernel{
-		X: kernel,
-		Y: kernel,
-	})
+	return kernel
 }
@@ -16,19 +16,19 @@ import (
 var blurOneColorTests = []transformOneColorTest{
 	{
 		"1x1-blank", 1, 1, 1, 1,
-		&BlurOptions{0.83, 1},
+		&BlurOptions{StdDev: 0.83, Size: 1},
 		[]uint8{0xff},
 		[]uint8{0xff},
 	},
 	{
 		"1x1-spreadblank", 1, 1, 1, 1,
-		&BlurOptions{0.83, 2},
+		&BlurOptions{StdDev: 0.83, Size: 2},
 		[]uint8{0xff},
 		[]uint8{0xff},
 	},
 	{
 		"3x3-blank", 3, 3, 3, 3,
-		&BlurOptions{0.83, 2},
+		&BlurOptions{StdDev: 0.83, Size: 2},
 		[]uint8{
 			0xff, 0xff, 0xff,
 			0xff, 0xff, 0xff,
@@ -42,7 +42,7 @@ var blurOneColorTests = []transformOneColorTest{
 	},
 	{
 		"3x3-dot", 3, 3, 3, 3,
-		&BlurOptions{0.34, 1},
+		&BlurOptions{StdDev: 0.34, Size: 1},
 		[]uint8{
 			0x00, 0x00, 0x00,
 			0x00, 0xff, 0x00,
@@ -56,7 +56,7 @@ var blurOneColorTests = []transformOneColorTest{
 	},
 	{
 		"5x5-dot", 5, 5, 5, 5,
-		&BlurOptions{0.34, 1},
+		&BlurOptions{StdDev: 0.34, Size: 1},
 		[]uint8{
 			0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00,
@@ -74,7 +74,7 @@ var blurOneColorTests = []transformOneColorTest{
 	},
 	{
 		"5x5-dot-spread", 5, 5, 5, 5,
-		&BlurOptions{0.85, 1},
+		&BlurOptions{StdDev: 0.85, Size: 1},
 		[]uint8{
 			0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00,
@@ -92,7 +92,7 @@ var blurOneColorTests = []transformOneColorTest{
 	},
 	{
 		"4x4-box", 4, 4, 4, 4,
-		&BlurOptions{0.34, 1},
+		&BlurOptions{StdDev: 0.34, Size: 1},
 		[]uint8{
 			0x00, 0x00, 0x00, 0x00,
 			0x00, 0xff, 0xff, 0x00,
@@ -108,7 +108,7 @@ var blurOneColorTests = []transformOneColorTest{
 	},
 	{
 		"5x5-twodots", 5, 5, 5, 5,
-		&BlurOptions{0.34, 1},
+		&BlurOptions{StdDev: 0.34, Size: 1},
 		[]uint8{
 			0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00,
@@ -148,6 +148,35 @@ func TestBlurEmpty(t *testing.T) {
 	}
 }
 
+func TestBlurAnisotropic(t *testing.T) {
+	src := graphicstest.MakeRGBA([]uint8{
+		0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0xff, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00,
+	}, 5)
+	methods := []BlurMethod{BlurKernel, BlurBox, BlurRecursive}
+	for _, m := range methods {
+		dst := image.NewRGBA(src.Bounds())
+		err := Blur(dst, src, &BlurOptions{StdDevX: 2, Method: m})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for y := 0; y < 5; y++ {
+			for x := 0; x < 5; x++ {
+				r := dst.RGBAAt(x, y).R
+				if y != 2 && r != 0 {
+					t.Errorf("method %d: (%d, %d) got %#x want 0", m, x, y, r)
+				}
+				if y == 2 && r == 0 {
+					t.Errorf("method %d: (%d, %d) got %#x", m, x, y, r)
+				}
+			}
+		}
+	}
+}
+
 func TestBlurGopher(t *testing.T) {
 	src, err := graphicstest.LoadImage("../testdata/gopher.png")
 	if err != nil {
@@ -188,7 +217,7 @@ func benchBlur(b *testing.B, bounds image.Rectangle) {
 
 	b.StartTimer()
 	for i := 0; i < b.N; i++ {
-		Blur(dst, src, &BlurOptions{0.84, 3})
+		Blur(dst, src, &BlurOptions{StdDev: 0.84, Size: 3})
 	}
 }
 
@@ -0,0 +1,188 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// BoxBlur produces a blurred version of the image, where each pixel is the
+// mean of the (2*radius+1)x(2*radius+1) square around it. It uses running
+// sums, so its cost does not depend on radius.
+func BoxBlur(dst draw.Image, src image.Image, radius int) error {
+	if dst == nil {
+		return errors.New("graphics: dst is nil")
+	}
+	if src == nil {
+		return errors.New("graphics: src is nil")
+	}
+	if radius < 0 {
+		return errors.New("graphics: radius is negative")
+	}
+	return boxBlur(dst, src, []int{radius}, []int{radius})
+}
+
+// boxRadii returns the radii of n successive box blurs that approximate a
+// Gaussian blur with standard deviation sd.
+//
+// See W. Wells, Efficient Synthesis of Gaussian Filters by Cascaded Uniform
+// Filters, IEEE Trans. PAMI, 1986.
+func boxRadii(sd float64, n int) []int {
+	// The ideal box width, and the odd widths on either side of it.
+	wIdeal := math.Sqrt(12*sd*sd/float64(n) + 1)
+	wl := int(wIdeal)
+	if wl%2 == 0 {
+		wl--
+	}
+	wu := wl + 2
+
+	// m boxes of width wl and n-m of width wu give the closest variance.
+	fl := float64(wl)
+	m := int(math.Floor((12*sd*sd-float64(n)*(fl*fl+4*fl+3))/(-4*fl-4) + 0.5))
+	radii := make([]int, n)
+	for i := range radii {
+		if i < m {
+			radii[i] = (wl - 1) / 2
+		} else {
+			radii[i] = (wu - 1) / 2
+		}
+	}
+	return radii
+}
+
+// boxBlur blurs src into dst with successive box blurs. Each pass blurs
+// horizontally with a radius from rx, and vertically with one from ry.
+func boxBlur(dst draw.Image, src image.Image, rx, ry []int) error {
+	b := dst.Bounds()
+	if b.Empty() {
+		return nil
+	}
+	w, h := b.Dx(), b.Dy()
+	buf := loadRGBA(src, b)
+	n := w
+	if h > n {
+		n = h
+	}
+	sum := make([]float64, n+1)
+	for i := 0; i < len(rx) || i < len(ry); i++ {
+		var r0, r1 int
+		if i < len(rx) {
+			r0 = rx[i]
+		}
+		if i < len(ry) {
+			r1 = ry[i]
+		}
+		eachLine(buf, w, h, func(v []float64, n, step int) {
+			if r0 > 0 {
+				boxLine(v, n, step, r0, sum)
+			}
+		}, func(v []float64, n, step int) {
+			if r1 > 0 {
+				boxLine(v, n, step, r1, sum)
+			}
+		})
+	}
+	storeRGBA(dst, buf)
+	return nil
+}
+
+// eachLine calls fx for every channel of every row, and then fy for every
+// channel of every column, of buf, as loaded by loadRGBA with width w and
+// height h. The line passed has n values, step apart.
+func eachLine(buf []float64, w, h int, fx, fy func(v []float64, n, step int)) {
+	for y := 0; y < h; y++ {
+		for c := 0; c < 4; c++ {
+			fx(buf[y*w*4+c:], w, 4)
+		}
+	}
+	for x := 0; x < w; x++ {
+		for c := 0; c < 4; c++ {
+			fy(buf[x*4+c:], h, w*4)
+		}
+	}
+}
+
+// boxLine replaces each of the n values of v, step apart, with the mean of
+// the 2*r+1 values around it. Values beyond either end of the line are
+// replaced with the central value, to avoid vignetting. sum must have room
+// for n+1 prefix sums.
+func boxLine(v []float64, n, step, r int, sum []float64) {
+	sum[0] = 0
+	for i := 0; i < n; i++ {
+		sum[i+1] = sum[i] + v[i*step]
+	}
+	size := 2*r + 1
+	for i := 0; i < n; i++ {
+		lo, hi := i-r, i+r+1
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > n {
+			hi = n
+		}
+		missing := float64(size - (hi - lo))
+		v[i*step] = (sum[hi] - sum[lo] + missing*v[i*step]) / float64(size)
+	}
+}
+
+// loadRGBA returns the 8-bit RGBA values of src within b, as floats.
+func loadRGBA(src image.Image, b image.Rectangle) []float64 {
+	w, h := b.Dx(), b.Dy()
+	buf := make([]float64, w*h*4)
+	if m, ok := src.(*image.RGBA); ok && b.In(m.Rect) {
+		for y := 0; y < h; y++ {
+			off := (y+b.Min.Y-m.Rect.Min.Y)*m.Stride + (b.Min.X-m.Rect.Min.X)*4
+			for i, p := range m.Pix[off : off+w*4] {
+				buf[y*w*4+i] = float64(p)
+			}
+		}
+		return buf
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, a := src.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			o := (y*w + x) * 4
+			buf[o+0] = float64(r >> 8)
+			buf[o+1] = float64(g >> 8)
+			buf[o+2] = float64(bl >> 8)
+			buf[o+3] = float64(a >> 8)
+		}
+	}
+	return buf
+}
+
+// storeRGBA writes buf, as loaded by loadRGBA, to dst, clamping to the range
+// [0, 255].
+func storeRGBA(dst draw.Image, buf []float64) {
+	b := dst.Bounds()
+	m, ok := dst.(*image.RGBA)
+	if !ok {
+		m = image.NewRGBA(b)
+	}
+	w := b.Dx()
+	for y := 0; y < b.Dy(); y++ {
+		off := y * m.Stride
+		for i, v := range buf[y*w*4 : (y+1)*w*4] {
+			m.Pix[off+i] = uint8(clamp(v+0.5, 0, 0xff))
+		}
+	}
+	if !ok {
+		draw.Draw(dst, b, m, b.Min, draw.Src)
+	}
+}
+
+// clamp clamps x to the range [x0, x1].
+func clamp(x, x0, x1 float64) float64 {
+	if x < x0 {
+		return x0
+	}
+	if x > x1 {
+		return x1
+	}
+	return x
+}
@@ -0,0 +1,105 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"github.com/BurntSushi/graphics-go/graphics/convolve"
+	"github.com/BurntSushi/graphics-go/graphics/graphicstest"
+	"image"
+	"reflect"
+	"testing"
+
+	_ "image/png"
+)
+
+func TestBoxBlurConvolve(t *testing.T) {
+	src, err := graphicstest.LoadImage("../testdata/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := src.Bounds()
+
+	dst := image.NewRGBA(b)
+	if err := BoxBlur(dst, src, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	box := make([]float64, 5)
+	for i := range box {
+		box[i] = 1.0 / 5
+	}
+	cmp := image.NewRGBA(b)
+	err = convolve.Convolve(cmp, src, &convolve.SeparableKernel{X: box, Y: box})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := graphicstest.ImageWithinTolerance(dst, cmp, 0x101); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBoxBlurZero(t *testing.T) {
+	src := graphicstest.MakeRGBA([]uint8{
+		0x10, 0x20, 0x30,
+		0x40, 0x50, 0x60,
+	}, 3)
+	dst := image.NewRGBA(src.Bounds())
+	if err := BoxBlur(dst, src, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := graphicstest.ImageWithinTolerance(dst, src, 0); err != nil {
+		t.Error(err)
+	}
+	if err := BoxBlur(dst, src, -1); err == nil {
+		t.Error("negative radius: got nil error")
+	}
+}
+
+func TestBoxRadii(t *testing.T) {
+	tests := []struct {
+		sd    float64
+		radii []int
+	}{
+		{0, []int{0, 0, 0}},
+		{1, []int{0, 0, 1}},
+		{2, []int{1, 1, 2}},
+		{40, []int{39, 39, 40}},
+	}
+	for _, tt := range tests {
+		if got := boxRadii(tt.sd, 3); !reflect.DeepEqual(got, tt.radii) {
+			t.Errorf("sd %v: got %v want %v", tt.sd, got, tt.radii)
+		}
+	}
+}
+
+func TestBlurBoxGopher(t *testing.T) {
+	src, err := graphicstest.LoadImage("../testdata/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := src.Bounds()
+
+	for _, sd := range []float64{2, 5} {
+		dst := image.NewRGBA(b)
+		if err := Blur(dst, src, &BlurOptions{StdDev: sd, Method: BlurBox}); err != nil {
+			t.Fatal(err)
+		}
+		cmp := image.NewRGBA(b)
+		if err := Blur(cmp, src, &BlurOptions{StdDev: sd}); err != nil {
+			t.Fatal(err)
+		}
+		if err := graphicstest.ImageWithinTolerance(dst, cmp, 0x1000); err != nil {
+			t.Errorf("sd %v: %v", sd, err)
+		}
+	}
+}
+
+func BenchmarkBlurBox400x400x40(b *testing.B) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 400))
+	dst := image.NewRGBA(src.Bounds())
+	for i := 0; i < b.N; i++ {
+		Blur(dst, src, &BlurOptions{StdDev: 40, Method: BlurBox})
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"errors"
+	"github.com/BurntSushi/graphics-go/graphics/convolve"
+	"image"
+	"math"
+)
+
+// CannyStdDev is the standard deviation of the blur Canny applies to
+// remove noise before finding edges.
+var CannyStdDev = 1.4
+
+// Canny finds the edges in src with the Canny edge detector, and draws them
+// in dst as 0xff, on a background of 0x00.
+//
+// After blurring src, the gradient of its luminance is found with the Sobel
+// operator. Edges are thinned to the pixels whose gradient magnitude is a
+// local maximum across the edge. Pixels with a magnitude of at least high
+// are edges, as are those with a magnitude of at least low that connect to
+// another edge. On a luminance range of [0, 255], the Sobel magnitude is at
+// most about 1442.
+func Canny(dst *image.Gray, src image.Image, low, high float64) error {
+	if dst == nil {
+		return errors.New("graphics: dst is nil")
+	}
+	if src == nil {
+		return errors.New("graphics: src is nil")
+	}
+	if low > high {
+		return errors.New("graphics: low threshold above high threshold")
+	}
+
+	b := src.Bounds()
+	blurred := image.NewRGBA(b)
+	if err := Blur(blurred, src, &BlurOptions{StdDev: CannyStdDev}); err != nil {
+		return err
+	}
+	d, err := convolve.Gradient(blurred)
+	if err != nil {
+		return err
+	}
+
+	// Non-maximum suppression. strength is 2 for strong edges, 1 for weak
+	// edges and 0 otherwise.
+	w, h := b.Dx(), b.Dy()
+	mag := d.Mag.Pix
+	strength := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			m := mag[i]
+			if m < low || m == 0 {
+				continue
+			}
+
+			// Compare with the neighbors across the edge, along the
+			// gradient, quantized to one of four directions.
+			dx, dy := cannyDirection(d.Angle.Pix[i])
+			var m0, m1 float64
+			if x-dx >= 0 && x-dx < w && y-dy >= 0 && y-dy < h {
+				m0 = mag[(y-dy)*w+x-dx]
+			}
+			if x+dx >= 0 && x+dx < w && y+dy >= 0 && y+dy < h {
+				m1 = mag[(y+dy)*w+x+dx]
+			}
+			if m < m0 || m <= m1 {
+				continue
+			}
+			if m >= high {
+				strength[i] = 2
+			} else {
+				strength[i] = 1
+			}
+		}
+	}
+
+	// Hysteresis: follow weak edges that connect to strong edges.
+	var stack []int
+	for i, s := range strength {
+		if s == 2 {
+			stack = append(stack, i)
+		}
+	}
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		x, y := i%w, i/w
+		for ny := y - 1; ny <= y+1; ny++ {
+			for nx := x - 1; nx <= x+1; nx++ {
+				if nx < 0 || nx >= w || ny < 0 || ny >= h {
+					continue
+				}
+				if j := ny*w + nx; strength[j] == 1 {
+					strength[j] = 2
+					stack = append(stack, j)
+				}
+			}
+		}
+	}
+
+	db := dst.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if !image.Pt(x, y).In(db) {
+				continue
+			}
+			v := uint8(0)
+			if strength[(y-b.Min.Y)*w+x-b.Min.X] == 2 {
+				v = 0xff
+			}
+			dst.Pix[(y-db.Min.Y)*dst.Stride+x-db.Min.X] = v
+		}
+	}
+	return nil
+}
+
+// cannyDirection returns the unit step, along one of the horizontal,
+// vertical or diagonal directions, closest to the gradient angle.
+func cannyDirection(angle float64) (dx, dy int) {
+	// Fold the angle into [0, Pi), as opposite directions are equivalent.
+	if angle < 0 {
+		angle += math.Pi
+	}
+	switch {
+	case angle < math.Pi/8 || angle >= 7*math.Pi/8:
+		return 1, 0
+	case angle < 3*math.Pi/8:
+		return 1, 1
+	case angle < 5*math.Pi/8:
+		return 0, 1
+	}
+	return -1, 1
+}
@@ -0,0 +1,90 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"github.com/BurntSushi/graphics-go/graphics/graphicstest"
+	"image"
+	"image/draw"
+	"testing"
+
+	_ "image/png"
+)
+
+func TestCannySquare(t *testing.T) {
+	b := image.Rect(0, 0, 30, 30)
+	src := image.NewGray(b)
+	draw.Draw(src, image.Rect(10, 10, 20, 20), image.White, image.ZP, draw.Src)
+
+	dst := image.NewGray(b)
+	if err := Canny(dst, src, 100, 300); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each row through the middle of the square crosses exactly two edges,
+	// one on either side.
+	for y := 13; y < 17; y++ {
+		var xs []int
+		for x := 0; x < 30; x++ {
+			if dst.GrayAt(x, y).Y == 0xff {
+				xs = append(xs, x)
+			}
+		}
+		if len(xs) != 2 || xs[0] < 9 || xs[0] > 10 || xs[1] < 19 || xs[1] > 20 {
+			t.Errorf("row %d: edges at %v", y, xs)
+		}
+	}
+
+	// Far from the square, there are no edges.
+	for _, p := range []image.Point{{2, 2}, {15, 15}, {27, 5}} {
+		if v := dst.GrayAt(p.X, p.Y).Y; v != 0 {
+			t.Errorf("%v: got %#x want 0", p, v)
+		}
+	}
+}
+
+func TestCannyHysteresis(t *testing.T) {
+	// A step that fades from strong to weak contrast down the image.
+	b := image.Rect(0, 0, 20, 20)
+	src := image.NewGray(b)
+	for y := 0; y < 20; y++ {
+		for x := 10; x < 20; x++ {
+			src.Pix[y*src.Stride+x] = uint8(0xff - 8*y)
+		}
+	}
+
+	// With a high low threshold, only the strong top part is kept.
+	// Lowering it lets the edge be followed further down.
+	count := func(low float64) int {
+		dst := image.NewGray(b)
+		if err := Canny(dst, src, low, 400); err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for _, v := range dst.Pix {
+			if v != 0 {
+				n++
+			}
+		}
+		return n
+	}
+	if strict, loose := count(390), count(100); strict >= loose {
+		t.Errorf("got %d edge pixels with low 390, %d with low 100", strict, loose)
+	}
+}
+
+func TestCannyGopher(t *testing.T) {
+	src, err := graphicstest.LoadImage("../testdata/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := image.NewGray(src.Bounds())
+	if err := Canny(dst, src, 50, 150); err != nil {
+		t.Fatal(err)
+	}
+	if err := Canny(dst, src, 150, 50); err == nil {
+		t.Error("low > high: got nil error")
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package convolve
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Channel is a set of the channels of an RGBA image.
+type Channel uint8
+
+const (
+	Red Channel = 1 << iota
+	Green
+	Blue
+	Alpha
+
+	RGB  = Red | Green | Blue
+	RGBA = RGB | Alpha
+)
+
+// Options are optional parameters to ConvolveWith.
+type Options struct {
+	// Channels is the set of channels to convolve. The other channels are
+	// copied from src. If zero, all channels are convolved, except that
+	// alpha is copied if Abs, Scale or Bias is set.
+	Channels Channel
+
+	// Kernels holds a kernel for each of the red, green, blue and alpha
+	// channels. A nil entry means the kernel passed to ConvolveWith.
+	Kernels [4]Kernel
+
+	// Luminance, if true, convolves only the luma of src, as in YCbCr,
+	// leaving its chroma and alpha as they are. Channels and Kernels are
+	// ignored.
+	Luminance bool
+
+	// Abs, if true, takes the absolute value of each convolved color.
+	// Scale then multiplies it, and Bias is added, before it is clamped to
+	// the range [0, 255]. A zero Scale means 1. They do not apply to alpha,
+	// which is kept from src unless Channels includes it, as kernels whose
+	// weights add up to zero, such as derivatives and emboss, would make it
+	// zero.
+	Abs   bool
+	Scale float64
+	Bias  float64
+}
+
+// affine reports whether any of Abs, Scale and Bias is set.
+func (opt *Options) affine() bool {
+	return opt.Abs || opt.Scale != 0 || opt.Bias != 0
+}
+
+// convolve applies k to src, writing the result to dst, with the Abs, Scale
+// and Bias of opt.
+func (opt *Options) convolve(dst *image.RGBA, src image.Image, k Kernel) error {
+	if k == nil {
+		return ErrNilKernel
+	}
+	var w pixelWriter = rgbaWriter{dst}
+	if opt.affine() {
+		scale := opt.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		w = affineWriter{w, scale, opt.Bias, opt.Abs}
+	}
+	return convolve(w, src, k)
+}
+
+// ConvolveWith is like Convolve, but with options. A nil opt is the same as
+// Convolve.
+//
+// If alpha is convolved, color is convolved premultiplied, and any color
+// channel that exceeds alpha after convolution is clamped to it. Otherwise,
+// color is convolved straight, not premultiplied, and then given the alpha
+// of src, so that a transparent pixel lends its color, not black, to its
+// neighbors.
+func ConvolveWith(dst draw.Image, src image.Image, k Kernel, opt *Options) error {
+	if opt == nil {
+		return Convolve(dst, src, k)
+	}
+	if dst == nil || src == nil {
+		return ErrNilImage
+	}
+
+	channels := opt.Channels
+	if channels == 0 {
+		channels = RGBA
+		if opt.affine() {
+			channels = RGB
+		}
+	}
+	if !opt.Luminance && channels&Alpha == 0 {
+		return opt.convolveStraight(dst, src, k, channels)
+	}
+
+	b := dst.Bounds()
+	res := image.NewRGBA(b)
+	draw.Draw(res, b, src, b.Min, draw.Src)
+	var err error
+	if opt.Luminance {
+		err = convolveLuma(res, src, k, opt)
+	} else {
+		err = opt.convolveChannels(res, src, k, channels)
+	}
+	if err != nil {
+		return err
+	}
+	clampToAlpha(res)
+
+	draw.Draw(dst, b, res, b.Min, draw.Src)
+	return nil
+}
+
+// convolveStraight is ConvolveWith for channels that do not include alpha.
+func (opt *Options) convolveStraight(dst draw.Image, src image.Image, k Kernel, channels Channel) error {
+	// s holds the straight color of src. It is opaque, so that convolving
+	// it reads the color as it is.
+	bs := src.Bounds()
+	s := image.NewNRGBA(bs)
+	draw.Draw(s, bs, src, bs.Min, draw.Src)
+	for i := 3; i < len(s.Pix); i += 4 {
+		s.Pix[i] = 0xff
+	}
+
+	// The convolved channels of res are replaced by straight color, which
+	// is what the pixels of an *image.NRGBA hold.
+	b := dst.Bounds()
+	res := image.NewNRGBA(b)
+	draw.Draw(res, b, src, b.Min, draw.Src)
+	pix := &image.RGBA{Pix: res.Pix, Stride: res.Stride, Rect: res.Rect}
+	if err := opt.convolveChannels(pix, s, k, channels); err != nil {
+		return err
+	}
+
+	draw.Draw(dst, b, res, b.Min, draw.Src)
+	return nil
+}
+
+// convolveChannels convolves src by k, or by the kernels of opt, and copies
+// the given channels of the result to dst.
+func (opt *Options) convolveChannels(dst *image.RGBA, src image.Image, k Kernel, channels Channel) error {
+	b := dst.Bounds()
+	var shared *image.RGBA
+	for c := 0; c < 4; c++ {
+		if channels&(1<<uint(c)) == 0 {
+			continue
+		}
+		var conv *image.RGBA
+		if kc := opt.Kernels[c]; kc != nil {
+			conv = image.NewRGBA(b)
+			if err := opt.convolve(conv, src, kc); err != nil {
+				return err
+			}
+		} else {
+			if shared == nil {
+				shared = image.NewRGBA(b)
+				if err := opt.convolve(shared, src, k); err != nil {
+					return err
+				}
+			}
+			conv = shared
+		}
+		copyChannel(dst, conv, c)
+	}
+	return nil
+}
+
+// clampToAlpha clamps each color channel of m to its alpha, as colors are
+// premultiplied.
+func clampToAlpha(m *image.RGBA) {
+	b := m.Bounds()
+	for y := 0; y < b.Dy(); y++ {
+		pix := m.Pix[y*m.Stride : y*m.Stride+b.Dx()*4]
+		for i := 0; i < len(pix); i += 4 {
+			for c := 0; c < 3; c++ {
+				if pix[i+c] > pix[i+3] {
+					pix[i+c] = pix[i+3]
+				}
+			}
+		}
+	}
+}
+
+// copyChannel copies channel c, counting red as 0, of src to dst. They have
+// the same bounds.
+func copyChannel(dst, src *image.RGBA, c int) {
+	b := dst.Bounds()
+	for y := 0; y < b.Dy(); y++ {
+		d := dst.Pix[y*dst.Stride : y*dst.Stride+b.Dx()*4]
+		s := src.Pix[y*src.Stride : y*src.Stride+b.Dx()*4]
+		for i := c; i < len(d); i += 4 {
+			d[i] = s[i]
+		}
+	}
+}
+
+// convolveLuma convolves the luma of src by k, and adds the change in luma
+// to each color channel of dst, which holds a copy of src. With the chroma
+// held fixed, a change in luma changes red, green and blue alike.
+func convolveLuma(dst *image.RGBA, src image.Image, k Kernel, opt *Options) error {
+	bs := src.Bounds()
+	y := image.NewGray(bs)
+	for py := bs.Min.Y; py < bs.Max.Y; py++ {
+		for px := bs.Min.X; px < bs.Max.X; px++ {
+			r, g, b, _ := src.At(px, py).RGBA()
+			l := (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 0x101
+			y.Pix[(py-bs.Min.Y)*y.Stride+px-bs.Min.X] = uint8(l + 0.5)
+		}
+	}
+
+	b := dst.Bounds()
+	conv := image.NewRGBA(b)
+	if err := opt.convolve(conv, y, k); err != nil {
+		return err
+	}
+	r := b.Intersect(bs)
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		for px := r.Min.X; px < r.Max.X; px++ {
+			off := (py-b.Min.Y)*dst.Stride + (px-b.Min.X)*4
+			d := float64(conv.Pix[off]) - float64(y.Pix[(py-bs.Min.Y)*y.Stride+px-bs.Min.X])
+			for c := 0; c < 3; c++ {
+				dst.Pix[off+c] = uint8(clamp(float64(dst.Pix[off+c])+d, 0, 0xff))
+			}
+		}
+	}
+	return nil
+}
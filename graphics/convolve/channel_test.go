@@ -0,0 +1,194 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package convolve
+
+import (
+	"github.com/BurntSushi/graphics-go/graphics/graphicstest"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestConvolveChannels(t *testing.T) {
+	src, err := graphicstest.LoadImage("../../testdata/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := src.Bounds()
+	box := &SeparableKernel{X: []float64{1. / 3, 1. / 3, 1. / 3}, Y: []float64{1}}
+
+	// Blurring only color leaves alpha as it is.
+	dst := image.NewRGBA(b)
+	if err := ConvolveWith(dst, src, box, &Options{Channels: RGB}); err != nil {
+		t.Fatal(err)
+	}
+	full := image.NewRGBA(b)
+	if err := Convolve(full, src, box); err != nil {
+		t.Fatal(err)
+	}
+	opaque := func(x, y int) bool {
+		_, _, _, a := src.At(x, y).RGBA()
+		return a == 0xffff || !image.Pt(x, y).In(b)
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			_, _, _, a := src.At(x, y).RGBA()
+			c := dst.RGBAAt(x, y)
+			if c.A != uint8(a>>8) {
+				t.Fatalf("(%d, %d): alpha %#x want %#x", x, y, c.A, a>>8)
+			}
+			// Where all the pixels under the kernel are opaque, straight
+			// and premultiplied color are the same.
+			if !opaque(x-1, y) || !opaque(x, y) || !opaque(x+1, y) {
+				continue
+			}
+			if want := full.RGBAAt(x, y).R; c.R != want {
+				t.Fatalf("(%d, %d): red %#x want %#x", x, y, c.R, want)
+			}
+		}
+	}
+}
+
+func TestConvolveStraight(t *testing.T) {
+	// Opaque red next to transparent red.
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	for x := 0; x < 4; x++ {
+		a := uint8(0xff)
+		if x >= 2 {
+			a = 0
+		}
+		src.SetNRGBA(x, 0, color.NRGBA{0xff, 0, 0, a})
+	}
+
+	// Blurring only color keeps the opaque pixels red.
+	box := &SeparableKernel{X: []float64{1. / 3, 1. / 3, 1. / 3}, Y: []float64{1}}
+	dst := image.NewNRGBA(src.Bounds())
+	if err := ConvolveWith(dst, src, box, &Options{Channels: RGB}); err != nil {
+		t.Fatal(err)
+	}
+	for x := 0; x < 2; x++ {
+		if c, want := dst.NRGBAAt(x, 0), (color.NRGBA{0xff, 0, 0, 0xff}); c != want {
+			t.Errorf("%d: got %v want %v", x, c, want)
+		}
+	}
+}
+
+func TestConvolveKernels(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 3, 1))
+	src.SetRGBA(1, 0, color.RGBA{0x80, 0x80, 0x80, 0xff})
+	src.SetRGBA(0, 0, color.RGBA{0, 0, 0, 0xff})
+	src.SetRGBA(2, 0, color.RGBA{0, 0, 0, 0xff})
+
+	// Shift red to the right, and leave the other channels alone.
+	shift := &SeparableKernel{X: []float64{1, 0, 0}, Y: []float64{1}}
+	ident := &SeparableKernel{X: []float64{1}, Y: []float64{1}}
+	dst := image.NewRGBA(src.Bounds())
+	opt := &Options{Kernels: [4]Kernel{shift}}
+	if err := ConvolveWith(dst, src, ident, opt); err != nil {
+		t.Fatal(err)
+	}
+	want := []color.RGBA{
+		{0, 0, 0, 0xff},
+		{0, 0x80, 0x80, 0xff},
+		{0x80, 0, 0, 0xff},
+	}
+	for x, w := range want {
+		if got := dst.RGBAAt(x, 0); got != w {
+			t.Errorf("%d: got %v want %v", x, got, w)
+		}
+	}
+
+	if err := ConvolveWith(dst, src, nil, &Options{}); err == nil {
+		t.Error("nil kernel: got nil error")
+	}
+}
+
+func TestConvolveLuminance(t *testing.T) {
+	src, err := graphicstest.LoadImage("../../testdata/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := src.Bounds()
+
+	// The identity kernel leaves the image as it is.
+	ident := &SeparableKernel{X: []float64{1}, Y: []float64{1}}
+	dst := image.NewRGBA(b)
+	if err := ConvolveWith(dst, src, ident, &Options{Luminance: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := graphicstest.ImageWithinTolerance(dst, src, 0); err != nil {
+		t.Error(err)
+	}
+
+	// A blur keeps the differences between color channels, where they are
+	// not clamped.
+	box := &SeparableKernel{X: []float64{0.2, 0.2, 0.2, 0.2, 0.2}, Y: []float64{1}}
+	if err := ConvolveWith(dst, src, box, &Options{Luminance: true}); err != nil {
+		t.Fatal(err)
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, _, a := src.At(x, y).RGBA()
+			c := dst.RGBAAt(x, y)
+			if c.R == 0 || c.G == 0 || c.R == c.A || c.G == c.A {
+				continue
+			}
+			want := int(r>>8) - int(g>>8)
+			if got := int(c.R) - int(c.G); got != want {
+				t.Fatalf("(%d, %d): red-green %d want %d", x, y, got, want)
+			}
+			if c.A != uint8(a>>8) {
+				t.Fatalf("(%d, %d): alpha %#x want %#x", x, y, c.A, a>>8)
+			}
+		}
+	}
+}
+
+func TestConvolveBias(t *testing.T) {
+	src := graphicstest.MakeRGBA([]uint8{
+		0x40, 0x40, 0x40, 0x40,
+		0x40, 0x40, 0x40, 0x40,
+		0x40, 0x40, 0x80, 0x80,
+		0x40, 0x40, 0x80, 0x80,
+	}, 4)
+	emboss, err := ParseKernel("-1 -1 0; -1 0 1; 0 1 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Flat areas are mid gray, and edges are lighter or darker.
+	dst := image.NewRGBA(src.Bounds())
+	opt := &Options{Channels: RGB, Scale: 0.5, Bias: 0x80}
+	if err := ConvolveWith(dst, src, emboss, opt); err != nil {
+		t.Fatal(err)
+	}
+	if c := dst.RGBAAt(0, 0); c != (color.RGBA{0x80, 0x80, 0x80, 0xff}) {
+		t.Errorf("flat: got %v", c)
+	}
+	if c := dst.RGBAAt(2, 2); c.R <= 0x80 || c.A != 0xff {
+		t.Errorf("edge: got %v", c)
+	}
+
+	// The absolute value of the Laplacian is bright on both sides of an
+	// edge.
+	opt = &Options{Channels: RGB, Abs: true}
+	if err := ConvolveWith(dst, src, Laplacian(), opt); err != nil {
+		t.Fatal(err)
+	}
+	if v := dst.RGBAAt(1, 2).R; v != 0x40 {
+		t.Errorf("outside edge: got %#x want 0x40", v)
+	}
+	if v := dst.RGBAAt(2, 2).R; v != 0x80 {
+		t.Errorf("inside edge: got %#x want 0x80", v)
+	}
+
+	// Without Channels, alpha is still kept.
+	if err := ConvolveWith(dst, src, Laplacian(), &Options{Abs: true}); err != nil {
+		t.Fatal(err)
+	}
+	if c := dst.RGBAAt(2, 2); c != (color.RGBA{0x80, 0x80, 0x80, 0xff}) {
+		t.Errorf("default channels: got %v", c)
+	}
+}
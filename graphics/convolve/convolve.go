@@ -12,6 +12,13 @@ import (
 	"math"
 )
 
+var (
+	// ErrNilImage is returned when dst or src is nil.
+	ErrNilImage = errors.New("graphics: nil image")
+	// ErrNilKernel is returned when the kernel is nil.
+	ErrNilKernel = errors.New("graphics: nil kernel")
+)
+
 // clamp clamps x to the range [x0, x1].
 func clamp(x, x0, x1 float64) float64 {
 	if x < x0 {
@@ -23,40 +30,73 @@ func clamp(x, x0, x1 float64) float64 {
 	return x
 }
 
-// Kernel is a square matrix that defines a convolution.
+// Kernel is a matrix of weights that defines a convolution. Unless it is
+// also a RectKernel, the matrix is square with an odd size, and the middle
+// weight is the weight for the output pixel. A RectKernel gives any other
+// size and anchor with Shape.
 type Kernel interface {
-	// Weights returns the square matrix of weights in row major order.
+	// Weights returns the matrix of weights in row major order.
 	Weights() []float64
 }
 
-// SeparableKernel is a linearly separable, square convolution kernel.
-// X and Y are the per-axis weights. Each slice must be the same length, and
-// have an odd length. The middle element of each slice is the weight for the
-// central pixel. For example, the horizontal Sobel kernel is:
+// RectKernel is a rectangular convolution kernel. Its weights are Size().X
+// wide and Size().Y high, in row major order, and the weight at Anchor() is
+// the weight for the output pixel.
+type RectKernel interface {
+	Kernel
+	// Shape returns the width and height of the matrix of weights, and the
+	// position in it of the output pixel's weight.
+	Shape() (size, anchor image.Point)
+}
+
+// SeparableKernel is a linearly separable, rectangular convolution kernel.
+// X and Y are the per-axis weights. Anchor is the position, within X and Y,
+// of the weight for the output pixel. If nil, it is the middle element of
+// each slice, len/2. For example, the horizontal Sobel kernel is:
 //	sobelX := &SeparableKernel{
 //		X: []float64{-1, 0, +1},
 //		Y: []float64{1, 2, 1},
 //	}
 type SeparableKernel struct {
-	X, Y []float64
+	X, Y   []float64
+	Anchor *image.Point
 }
 
 func (k *SeparableKernel) Weights() []float64 {
-	n := len(k.X)
-	w := make([]float64, n*n)
-	for y := 0; y < n; y++ {
-		for x := 0; x < n; x++ {
-			w[y*n+x] = k.X[x] * k.Y[y]
+	w := make([]float64, len(k.X)*len(k.Y))
+	for y := range k.Y {
+		for x := range k.X {
+			w[y*len(k.X)+x] = k.X[x] * k.Y[y]
 		}
 	}
 	return w
 }
 
-// fullKernel is a square convolution kernel.
+// Shape returns the lengths of X and Y, and the anchor.
+func (k *SeparableKernel) Shape() (size, anchor image.Point) {
+	size = image.Pt(len(k.X), len(k.Y))
+	if k.Anchor != nil {
+		return size, *k.Anchor
+	}
+	return size, image.Pt(len(k.X)/2, len(k.Y)/2)
+}
+
+// fullKernel is a square convolution kernel, anchored at its middle. Other
+// shapes are a rectKernel.
 type fullKernel []float64
 
 func (k fullKernel) Weights() []float64 { return k }
 
+// rectKernel is a rectangular convolution kernel.
+type rectKernel struct {
+	w            []float64
+	size, anchor image.Point
+}
+
+func (k *rectKernel) Weights() []float64 { return k.w }
+
+func (k *rectKernel) Shape() (size, anchor image.Point) { return k.size, k.anchor }
+
 func kernelSize(w []float64) (size int, err error) {
 	size = int(math.Sqrt(float64(len(w))))
 	if size*size != len(w) {
@@ -68,6 +108,37 @@ func kernelSize(w []float64) (size int, err error) {
 	return size, nil
 }
 
+// kernelShape returns the weights, size and anchor of k.
+func kernelShape(k Kernel) (w []float64, size, anchor image.Point, err error) {
+	w = k.Weights()
+	rk, ok := k.(RectKernel)
+	if !ok {
+		n, err := kernelSize(w)
+		if err != nil {
+			return nil, size, anchor, err
+		}
+		return w, image.Pt(n, n), image.Pt(n/2, n/2), nil
+	}
+	size, anchor = rk.Shape()
+	if err = checkShape(len(w), size, anchor); err != nil {
+		return nil, size, anchor, err
+	}
+	return w, size, anchor, nil
+}
+
+func checkShape(n int, size, anchor image.Point) error {
+	if size.X < 1 || size.Y < 1 {
+		return fmt.Errorf("graphics: kernel size %v is empty", size)
+	}
+	if size.X*size.Y != n {
+		return fmt.Errorf("graphics: kernel has %d weights, not %v", n, size)
+	}
+	if !anchor.In(image.Rectangle{image.ZP, size}) {
+		return fmt.Errorf("graphics: kernel anchor %v outside size %v", anchor, size)
+	}
+	return nil
+}
+
 // NewKernel returns a square convolution kernel.
 func NewKernel(w []float64) (Kernel, error) {
 	if _, err := kernelSize(w); err != nil {
@@ -76,14 +147,68 @@ func NewKernel(w []float64) (Kernel, error) {
 	return fullKernel(w), nil
 }
 
-func convolveRGBASep(dst *image.RGBA, src image.Image, k *SeparableKernel) error {
-	if len(k.X) != len(k.Y) {
-		return fmt.Errorf("graphics: kernel not square (x %d, y %d)", len(k.X), len(k.Y))
+// NewRectKernel returns a rectangular convolution kernel of the given size,
+// with weights w in row major order. anchor is the position of the weight
+// for the output pixel.
+func NewRectKernel(w []float64, size, anchor image.Point) (RectKernel, error) {
+	if err := checkShape(len(w), size, anchor); err != nil {
+		return nil, err
 	}
-	if len(k.X)%2 != 1 {
-		return fmt.Errorf("graphics: kernel length (%d) not odd", len(k.X))
+	return &rectKernel{w, size, anchor}, nil
+}
+
+// Separate returns the separable kernel equal to k, and whether there is
+// one. k is separable if its matrix of weights is the outer product of a
+// column and a row, which is to say that it has rank one.
+func Separate(k Kernel) (*SeparableKernel, bool) {
+	if sk, ok := k.(*SeparableKernel); ok {
+		return sk, true
+	}
+	w, size, anchor, err := kernelShape(k)
+	if err != nil {
+		return nil, false
+	}
+
+	// Factor k through its largest weight, p at (px, py). X is the row of
+	// p, and Y is the column of p divided by p.
+	px, py, p := 0, 0, 0.0
+	for i, f := range w {
+		if math.Abs(f) > math.Abs(p) {
+			px, py, p = i%size.X, i/size.X, f
+		}
+	}
+	sk := &SeparableKernel{
+		X: make([]float64, size.X),
+		Y: make([]float64, size.Y),
+	}
+	if anchor != image.Pt(size.X/2, size.Y/2) {
+		sk.Anchor = &anchor
+	}
+	if p == 0 {
+		return sk, true
+	}
+	copy(sk.X, w[py*size.X:(py+1)*size.X])
+	for y := range sk.Y {
+		sk.Y[y] = w[y*size.X+px] / p
+	}
+
+	// Every weight must be the product, up to rounding error.
+	tol := math.Abs(p) * 1e-9
+	for y, fy := range sk.Y {
+		for x, fx := range sk.X {
+			if math.Abs(w[y*size.X+x]-fx*fy) > tol {
+				return nil, false
+			}
+		}
+	}
+	return sk, true
+}
+
+func convolveSep(dst pixelWriter, src image.Image, k *SeparableKernel) error {
+	size, anchor := k.Shape()
+	if err := checkShape(size.X*size.Y, size, anchor); err != nil {
+		return err
 	}
-	radius := (len(k.X) - 1) / 2
 
 	// buf holds the result of vertically blurring src.
 	bounds := dst.Bounds()
@@ -92,32 +217,21 @@ func convolveRGBASep(dst *image.RGBA, src image.Image, k *SeparableKernel) error
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
 			var r, g, b, a float64
-			// k0 is the kernel weight for the center pixel. This may be greater
-			// than kernel[0], near the boundary of the source image, to avoid
-			// vignetting.
-			k0 := k.X[radius]
-
-			// Add the pixels from above.
-			for i := 1; i <= radius; i++ {
-				f := k.Y[radius-i]
-				if y-i < bounds.Min.Y {
-					k0 += f
-				} else {
-					or, og, ob, oa := src.At(x, y-i).RGBA()
-					r += float64(or>>8) * f
-					g += float64(og>>8) * f
-					b += float64(ob>>8) * f
-					a += float64(oa>>8) * f
+			// k0 is the kernel weight for the anchor pixel. This may be
+			// greater than k.Y[anchor.Y], near the boundary of the source
+			// image, to avoid vignetting.
+			k0 := k.Y[anchor.Y]
+
+			// Add the pixels from above and below.
+			for i, f := range k.Y {
+				if i == anchor.Y {
+					continue
 				}
-			}
-
-			// Add the pixels from below.
-			for i := 1; i <= radius; i++ {
-				f := k.Y[radius+i]
-				if y+i >= bounds.Max.Y {
+				sy := y + i - anchor.Y
+				if sy < bounds.Min.Y || sy >= bounds.Max.Y {
 					k0 += f
 				} else {
-					or, og, ob, oa := src.At(x, y+i).RGBA()
+					or, og, ob, oa := src.At(x, sy).RGBA()
 					r += float64(or>>8) * f
 					g += float64(og>>8) * f
 					b += float64(ob>>8) * f
@@ -125,7 +239,7 @@ func convolveRGBASep(dst *image.RGBA, src image.Image, k *SeparableKernel) error
 				}
 			}
 
-			// Add the central pixel.
+			// Add the anchor pixel.
 			or, og, ob, oa := src.At(x, y).RGBA()
 			r += float64(or>>8) * k0
 			g += float64(og>>8) * k0
@@ -145,29 +259,18 @@ func convolveRGBASep(dst *image.RGBA, src image.Image, k *SeparableKernel) error
 	for y := 0; y < height; y++ {
 		for x := 0; x < width; x++ {
 			var r, g, b, a float64
-			k0, off := k.X[radius], y*width*4+x*4
+			k0, off := k.X[anchor.X], y*width*4+x*4
 
-			// Add the pixels from the left.
-			for i := 1; i <= radius; i++ {
-				f := k.X[radius-i]
-				if x-i < 0 {
-					k0 += f
-				} else {
-					o := off - i*4
-					r += buf[o+0] * f
-					g += buf[o+1] * f
-					b += buf[o+2] * f
-					a += buf[o+3] * f
+			// Add the pixels from the left and right.
+			for i, f := range k.X {
+				if i == anchor.X {
+					continue
 				}
-			}
-
-			// Add the pixels from the right.
-			for i := 1; i <= radius; i++ {
-				f := k.X[radius+i]
-				if x+i >= width {
+				sx := x + i - anchor.X
+				if sx < 0 || sx >= width {
 					k0 += f
 				} else {
-					o := off + i*4
+					o := off + (i-anchor.X)*4
 					r += buf[o+0] * f
 					g += buf[o+1] * f
 					b += buf[o+2] * f
@@ -175,34 +278,131 @@ func convolveRGBASep(dst *image.RGBA, src image.Image, k *SeparableKernel) error
 				}
 			}
 
-			// Add the central pixel.
+			// Add the anchor pixel.
 			r += buf[off+0] * k0
 			g += buf[off+1] * k0
 			b += buf[off+2] * k0
 			a += buf[off+3] * k0
 
-			// Write to dst, clamping to the range [0, 255].
-			dstOff := (y-dst.Rect.Min.Y)*dst.Stride + (x-dst.Rect.Min.X)*4
-			dst.Pix[dstOff+0] = uint8(clamp(r+0.5, 0, 255))
-			dst.Pix[dstOff+1] = uint8(clamp(g+0.5, 0, 255))
-			dst.Pix[dstOff+2] = uint8(clamp(b+0.5, 0, 255))
-			dst.Pix[dstOff+3] = uint8(clamp(a+0.5, 0, 255))
+			dst.set(bounds.Min.X+x, bounds.Min.Y+y, r, g, b, a)
 		}
 	}
 
 	return nil
 }
 
-func convolveRGBA(dst *image.RGBA, src image.Image, k Kernel) error {
+// convolveSepFull is convolveSpatial for a separable kernel. Unlike
+// convolveSep, which folds the weights beyond the edges of dst onto the
+// anchor one axis at a time, it folds the weights beyond the edges of src
+// onto the source pixel under the anchor, as convolveSpatial does. The
+// weights inside src are the product of a run of X and a run of Y, so they
+// can still be applied one axis at a time.
+func convolveSepFull(dst pixelWriter, src image.Image, k *SeparableKernel) error {
+	size, anchor := k.Shape()
+	if err := checkShape(size.X*size.Y, size, anchor); err != nil {
+		return err
+	}
+	bs := src.Bounds()
+	out := dst.Bounds().Intersect(bs)
+	if out.Empty() {
+		return nil
+	}
+
+	// Columns x0 through x1-1 of src reach the output through the kernel.
+	x0 := maxInt(out.Min.X-anchor.X, bs.Min.X)
+	x1 := minInt(out.Max.X+size.X-1-anchor.X, bs.Max.X)
+
+	// buf holds the result of vertically convolving src, with zero beyond
+	// its edges.
+	width := x1 - x0
+	buf := make([]float64, width*out.Dy()*4)
+	for y := out.Min.Y; y < out.Max.Y; y++ {
+		for x := x0; x < x1; x++ {
+			var r, g, b, a float64
+			for i, f := range k.Y {
+				sy := y + i - anchor.Y
+				if sy < bs.Min.Y || sy >= bs.Max.Y {
+					continue
+				}
+				or, og, ob, oa := src.At(x, sy).RGBA()
+				r += float64(or>>8) * f
+				g += float64(og>>8) * f
+				b += float64(ob>>8) * f
+				a += float64(oa>>8) * f
+			}
+			o := (y-out.Min.Y)*width*4 + (x-x0)*4
+			buf[o+0] = r
+			buf[o+1] = g
+			buf[o+2] = b
+			buf[o+3] = a
+		}
+	}
+
+	var sumX, sumY float64
+	for _, f := range k.X {
+		sumX += f
+	}
+	for _, f := range k.Y {
+		sumY += f
+	}
+
+	for y := out.Min.Y; y < out.Max.Y; y++ {
+		// inY is the sum of the weights of Y inside src.
+		inY := 0.0
+		for i, f := range k.Y {
+			if sy := y + i - anchor.Y; sy >= bs.Min.Y && sy < bs.Max.Y {
+				inY += f
+			}
+		}
+		for x := out.Min.X; x < out.Max.X; x++ {
+			var r, g, b, a, inX float64
+			for i, f := range k.X {
+				sx := x + i - anchor.X
+				if sx < bs.Min.X || sx >= bs.Max.X {
+					continue
+				}
+				inX += f
+				o := (y-out.Min.Y)*width*4 + (sx-x0)*4
+				r += buf[o+0] * f
+				g += buf[o+1] * f
+				b += buf[o+2] * f
+				a += buf[o+3] * f
+			}
+
+			// The weights outside src apply to the central pixel.
+			if adj := sumX*sumY - inX*inY; adj != 0 {
+				sr, sg, sb, sa := src.At(x, y).RGBA()
+				r += float64(sr>>8) * adj
+				g += float64(sg>>8) * adj
+				b += float64(sb>>8) * adj
+				a += float64(sa>>8) * adj
+			}
+
+			dst.set(x, y, r, g, b, a)
+		}
+	}
+	return nil
+}
+
+func convolveFull(dst pixelWriter, src image.Image, k Kernel) error {
 	b := dst.Bounds()
 	bs := src.Bounds()
-	w := k.Weights()
-	size, err := kernelSize(w)
+	w, size, anchor, err := kernelShape(k)
 	if err != nil {
 		return err
 	}
-	radius := (size - 1) / 2
+	if fftWins(b.Intersect(bs), size) {
+		convolveFFT(dst, src, w, size, anchor)
+	} else {
+		convolveSpatial(dst, src, w, size, anchor)
+	}
+	return nil
+}
 
+// convolveSpatial is convolveFull, computed directly.
+func convolveSpatial(dst pixelWriter, src image.Image, w []float64, size, anchor image.Point) {
+	b := dst.Bounds()
+	bs := src.Bounds()
 	for y := b.Min.Y; y < b.Max.Y; y++ {
 		for x := b.Min.X; x < b.Max.X; x++ {
 			if !image.Pt(x, y).In(bs) {
@@ -210,9 +410,10 @@ func convolveRGBA(dst *image.RGBA, src image.Image, k Kernel) error {
 			}
 
 			var r, g, b, a, adj float64
-			for cy := y - radius; cy <= y+radius; cy++ {
-				for cx := x - radius; cx <= x+radius; cx++ {
-					factor := w[(cy-y+radius)*size+cx-x+radius]
+			for ky := 0; ky < size.Y; ky++ {
+				for kx := 0; kx < size.X; kx++ {
+					factor := w[ky*size.X+kx]
+					cx, cy := x+kx-anchor.X, y+ky-anchor.Y
 					if !image.Pt(cx, cy).In(bs) {
 						adj += factor
 					} else {
@@ -233,42 +434,48 @@ func convolveRGBA(dst *image.RGBA, src image.Image, k Kernel) error {
 				a += float64(sa>>8) * adj
 			}
 
-			off := (y-dst.Rect.Min.Y)*dst.Stride + (x-dst.Rect.Min.X)*4
-			dst.Pix[off+0] = uint8(clamp(r+0.5, 0, 0xff))
-			dst.Pix[off+1] = uint8(clamp(g+0.5, 0, 0xff))
-			dst.Pix[off+2] = uint8(clamp(b+0.5, 0, 0xff))
-			dst.Pix[off+3] = uint8(clamp(a+0.5, 0, 0xff))
+			dst.set(x, y, r, g, b, a)
 		}
 	}
-
-	return nil
 }
 
 // Convolve produces dst by applying the convolution kernel k to src.
-func Convolve(dst draw.Image, src image.Image, k Kernel) (err error) {
-	if dst == nil || src == nil || k == nil {
-		return nil
+// Kernels that Separate can factor are applied one axis at a time, with
+// the same result, up to rounding, as applying them whole. Other kernels
+// that are large for the size of dst are applied in the frequency domain,
+// which is faster but may differ from the direct result by one in each
+// channel.
+//
+// Convolution is of premultiplied color. It writes *image.RGBA,
+// *image.NRGBA, *image.RGBA64 and *image.Gray directly; other types of dst
+// go through a temporary *image.RGBA.
+func Convolve(dst draw.Image, src image.Image, k Kernel) error {
+	if dst == nil || src == nil {
+		return ErrNilImage
+	}
+	if k == nil {
+		return ErrNilKernel
 	}
 
-	b := dst.Bounds()
-	dstRgba, ok := dst.(*image.RGBA)
-	if !ok {
-		dstRgba = image.NewRGBA(b)
+	w, direct := newPixelWriter(dst)
+	if err := convolve(w, src, k); err != nil {
+		return err
 	}
+	if !direct {
+		b := dst.Bounds()
+		draw.Draw(dst, b, w.(rgbaWriter).RGBA, b.Min, draw.Src)
+	}
+	return nil
+}
 
+// convolve applies k to src, writing the result to dst.
+func convolve(dst pixelWriter, src image.Image, k Kernel) error {
 	switch k := k.(type) {
 	case *SeparableKernel:
-		err = convolveRGBASep(dstRgba, src, k)
-	default:
-		err = convolveRGBA(dstRgba, src, k)
+		return convolveSep(dst, src, k)
 	}
-
-	if err != nil {
-		return err
+	if sk, ok := Separate(k); ok {
+		return convolveSepFull(dst, src, sk)
 	}
-
-	if !ok {
-		draw.Draw(dst, b, dstRgba, b.Min, draw.Src)
-	}
-	return nil
+	return convolveFull(dst, src, k)
 }
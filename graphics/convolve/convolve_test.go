@@ -55,8 +55,9 @@ func TestConvolve(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	// Convolve would separate kernFull too, so apply it whole.
 	full := image.NewRGBA(b)
-	Convolve(full, src, kernFull)
+	convolveSpatial(rgbaWriter{full}, src, kernFull.Weights(), image.Pt(3, 3), image.Pt(1, 1))
 
 	err = graphicstest.ImageWithinTolerance(sep, full, 0x101)
 	if err != nil {
@@ -64,15 +65,233 @@ func TestConvolve(t *testing.T) {
 	}
 }
 
-func TestConvolveNil(t *testing.T) {
-	if err := Convolve(nil, nil, nil); err != nil {
+func TestConvolveSeparated(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i * 4)
+	}
+	w := make([]float64, 25)
+	col := []float64{1, 2, 3, 2, 1}
+	row := []float64{1, 4, 6, 4, 1}
+	for y, fy := range col {
+		for x, fx := range row {
+			w[y*5+x] = fx * fy / 144
+		}
+	}
+	k, err := NewKernel(w)
+	if err != nil {
 		t.Fatal(err)
 	}
+	off, err := NewRectKernel(w, image.Pt(5, 5), image.Pt(0, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A separable full kernel gives the same edge pixels as when it is
+	// applied whole, for a dst that matches, overlaps or lies in src.
+	for _, db := range []image.Rectangle{
+		src.Bounds(),
+		image.Rect(-3, 2, 5, 11),
+		image.Rect(2, 1, 6, 7),
+	} {
+		for _, k := range []Kernel{k, off} {
+			w, size, anchor, err := kernelShape(k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := image.NewRGBA(db)
+			convolveSpatial(rgbaWriter{want}, src, w, size, anchor)
+			got := image.NewRGBA(db)
+			if err := Convolve(got, src, k); err != nil {
+				t.Fatal(err)
+			}
+			if err := graphicstest.ImageWithinTolerance(got, want, 0x101); err != nil {
+				t.Errorf("dst %v, anchor %v: %v", db, anchor, err)
+			}
+		}
+	}
+}
+
+func TestConvolveNil(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	if err := Convolve(nil, nil, nil); err != ErrNilImage {
+		t.Errorf("nil images: got %v want %v", err, ErrNilImage)
+	}
+	if err := Convolve(m, nil, Laplacian()); err != ErrNilImage {
+		t.Errorf("nil src: got %v want %v", err, ErrNilImage)
+	}
+	if err := Convolve(m, m, nil); err != ErrNilKernel {
+		t.Errorf("nil kernel: got %v want %v", err, ErrNilKernel)
+	}
 }
 
 func TestConvolveEmpty(t *testing.T) {
 	empty := image.NewRGBA(image.Rect(0, 0, 0, 0))
-	if err := Convolve(empty, empty, nil); err != nil {
+	if err := Convolve(empty, empty, Laplacian()); err != nil {
 		t.Fatal(err)
 	}
 }
+
+func TestRectKernel(t *testing.T) {
+	src, err := graphicstest.LoadImage("../../testdata/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := src.Bounds()
+
+	// A 1x7 horizontal box, as a full and a separable kernel.
+	row := []float64{1, 1, 1, 1, 1, 1, 1}
+	for i := range row {
+		row[i] /= 7
+	}
+	kernFull, err := NewRectKernel(row, image.Pt(7, 1), image.Pt(3, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kernSep := &SeparableKernel{X: row, Y: []float64{1}}
+
+	full := image.NewRGBA(b)
+	if err := Convolve(full, src, kernFull); err != nil {
+		t.Fatal(err)
+	}
+	sep := image.NewRGBA(b)
+	if err := Convolve(sep, src, kernSep); err != nil {
+		t.Fatal(err)
+	}
+	if err := graphicstest.ImageWithinTolerance(sep, full, 0x101); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRobertsCross(t *testing.T) {
+	src := graphicstest.MakeRGBA([]uint8{
+		0x10, 0x10, 0x10,
+		0x10, 0x50, 0x10,
+		0x10, 0x10, 0x10,
+	}, 3)
+	k, err := NewRectKernel([]float64{
+		+1, 0,
+		0, -1,
+	}, image.Pt(2, 2), image.Pt(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := image.NewRGBA(src.Bounds())
+	if err := Convolve(dst, src, k); err != nil {
+		t.Fatal(err)
+	}
+
+	// The pixel up and to the left of the dot sees a negative gradient,
+	// which is clamped to zero. Beyond the edges, the missing weight falls
+	// on the output pixel.
+	want := []uint8{
+		0x00, 0x00, 0x00,
+		0x00, 0x40, 0x00,
+		0x00, 0x00, 0x00,
+	}
+	for i, w := range want {
+		if got := dst.Pix[i*4]; got != w {
+			t.Errorf("(%d, %d): got %#x want %#x", i%3, i/3, got, w)
+		}
+	}
+}
+
+func TestSeparableAnchor(t *testing.T) {
+	src := graphicstest.MakeRGBA([]uint8{
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x80, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	}, 4)
+
+	// An even-sized kernel, anchored on its first element, averages each
+	// pixel with the one to its right.
+	k := &SeparableKernel{
+		X:      []float64{0.5, 0.5},
+		Y:      []float64{1},
+		Anchor: &image.Point{0, 0},
+	}
+	dst := image.NewRGBA(src.Bounds())
+	if err := Convolve(dst, src, k); err != nil {
+		t.Fatal(err)
+	}
+	want := graphicstest.MakeRGBA([]uint8{
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x40, 0x40, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	}, 4)
+	if err := graphicstest.ImageWithinTolerance(dst, want, 0x101); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestKernelShapeInvalid(t *testing.T) {
+	if _, err := NewRectKernel([]float64{1, 2, 3}, image.Pt(2, 2), image.ZP); err == nil {
+		t.Error("wrong weight count: got nil error")
+	}
+	if _, err := NewRectKernel([]float64{1, 2}, image.Pt(2, 1), image.Pt(2, 0)); err == nil {
+		t.Error("anchor outside: got nil error")
+	}
+	m := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	k := &SeparableKernel{X: []float64{1}, Y: []float64{1}, Anchor: &image.Point{1, 0}}
+	if err := Convolve(m, m, k); err == nil {
+		t.Error("separable anchor outside: got nil error")
+	}
+}
+
+func TestConvolveOffset(t *testing.T) {
+	src, err := graphicstest.LoadImage("../../testdata/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := &SeparableKernel{X: []float64{1, 2, 1}, Y: []float64{0.25, 0.5, 0.25}}
+	for i := range k.X {
+		k.X[i] /= 4
+	}
+
+	// Convolving part of src matches the same part of a full convolution.
+	full := image.NewRGBA(src.Bounds())
+	if err := Convolve(full, src, k); err != nil {
+		t.Fatal(err)
+	}
+	r := image.Rect(100, 150, 300, 400)
+	part := image.NewRGBA(r)
+	if err := Convolve(part, src, k); err != nil {
+		t.Fatal(err)
+	}
+	inner := r.Inset(1)
+	err = graphicstest.ImageWithinTolerance(part.SubImage(inner), full.SubImage(inner), 0x101)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSeparate(t *testing.T) {
+	// An outer product, anchored off center.
+	x, y := []float64{1, 4, 6, 4, 1}, []float64{0.5, -1, 0.25}
+	w := (&SeparableKernel{X: x, Y: y}).Weights()
+	k, err := NewRectKernel(w, image.Pt(5, 3), image.Pt(1, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk, ok := Separate(k)
+	if !ok {
+		t.Fatal("outer product is not separable")
+	}
+	if got := sk.Weights(); !reflect.DeepEqual(got, w) {
+		t.Errorf("weights: got %v want %v", got, w)
+	}
+	if _, anchor := sk.Shape(); anchor != image.Pt(1, 2) {
+		t.Errorf("anchor: got %v want %v", anchor, image.Pt(1, 2))
+	}
+
+	if _, ok := Separate(Laplacian()); ok {
+		t.Error("Laplacian is separable")
+	}
+	zero, err := NewKernel(make([]float64, 9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sk, ok := Separate(zero); !ok || len(sk.X) != 3 || len(sk.Y) != 3 {
+		t.Errorf("zero kernel: got %v, %v", sk, ok)
+	}
+}
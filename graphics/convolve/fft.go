@@ -0,0 +1,194 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package convolve
+
+import (
+	"image"
+	"math"
+	"math/cmplx"
+)
+
+// nextPow2 returns the smallest power of two that is at least n.
+func nextPow2(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// fft computes the discrete Fourier transform of x in place. The length of
+// x must be a power of two. If inverse is true, it computes the inverse
+// transform, without dividing by len(x).
+func fft(x []complex128, inverse bool) {
+	n := len(x)
+
+	// Bit reversal permutation.
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	// Iterative radix-2 butterflies.
+	sign := -1.0
+	if inverse {
+		sign = 1.0
+	}
+	for size := 2; size <= n; size <<= 1 {
+		half := size / 2
+		step := cmplx.Rect(1, sign*2*math.Pi/float64(size))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < half; k++ {
+				a, b := x[start+k], x[start+k+half]*w
+				x[start+k] = a + b
+				x[start+k+half] = a - b
+				w *= step
+			}
+		}
+	}
+}
+
+// fft2 computes the two dimensional discrete Fourier transform of x in
+// place. x is w wide and h high, in row major order, and both w and h must
+// be powers of two. If inverse is true, it computes the inverse transform,
+// without dividing by w*h.
+func fft2(x []complex128, w, h int, inverse bool) {
+	for y := 0; y < h; y++ {
+		fft(x[y*w:(y+1)*w], inverse)
+	}
+	col := make([]complex128, h)
+	for c := 0; c < w; c++ {
+		for y := range col {
+			col[y] = x[y*w+c]
+		}
+		fft(col, inverse)
+		for y, v := range col {
+			x[y*w+c] = v
+		}
+	}
+}
+
+// fftWins reports whether convolving the pixels in out by a kernel of the
+// given size is likely to be faster in the frequency domain.
+func fftWins(out image.Rectangle, size image.Point) bool {
+	// The spatial path costs one multiply-add per weight per pixel. The
+	// five transforms over the padded area N, measured in the same units,
+	// cost about N*log2(N) together.
+	n := float64(nextPow2(out.Dx()+2*size.X) * nextPow2(out.Dy()+2*size.Y))
+	spatial := float64(out.Dx()*out.Dy()) * float64(size.X*size.Y)
+	freq := n * math.Log2(n)
+	return freq < spatial
+}
+
+// convolveFFT is convolveFull, computed in the frequency domain.
+func convolveFFT(dst pixelWriter, src image.Image, w []float64, size, anchor image.Point) {
+	bs := src.Bounds()
+	out := dst.Bounds().Intersect(bs)
+	if out.Empty() {
+		return
+	}
+
+	// r is the part of src that reaches the output through the kernel. The
+	// output pixel (x, y) reads from (x-anchor.X, y-anchor.Y) through
+	// (x+size.X-1-anchor.X, y+size.Y-1-anchor.Y).
+	r := image.Rect(
+		out.Min.X-anchor.X, out.Min.Y-anchor.Y,
+		out.Max.X+size.X-1-anchor.X, out.Max.Y+size.Y-1-anchor.Y,
+	).Intersect(bs)
+
+	// Pad to avoid wrap around.
+	pw := nextPow2(r.Dx() + size.X - 1)
+	ph := nextPow2(r.Dy() + size.Y - 1)
+
+	// Pack R and G into one complex plane, and B and A into another. The
+	// kernel is real, so the channels do not mix.
+	rg := make([]complex128, pw*ph)
+	ba := make([]complex128, pw*ph)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			sr, sg, sb, sa := src.At(x, y).RGBA()
+			i := (y-r.Min.Y)*pw + x - r.Min.X
+			rg[i] = complex(float64(sr>>8), float64(sg>>8))
+			ba[i] = complex(float64(sb>>8), float64(sa>>8))
+		}
+	}
+
+	// Lay out the kernel so that a circular convolution with it computes
+	// the correlation that convolveSpatial does.
+	kern := make([]complex128, pw*ph)
+	for ky := 0; ky < size.Y; ky++ {
+		for kx := 0; kx < size.X; kx++ {
+			x := (anchor.X - kx + pw) % pw
+			y := (anchor.Y - ky + ph) % ph
+			kern[y*pw+x] = complex(w[ky*size.X+kx], 0)
+		}
+	}
+
+	fft2(rg, pw, ph, false)
+	fft2(ba, pw, ph, false)
+	fft2(kern, pw, ph, false)
+	for i, k := range kern {
+		rg[i] *= k
+		ba[i] *= k
+	}
+	fft2(rg, pw, ph, true)
+	fft2(ba, pw, ph, true)
+	scale := 1 / float64(pw*ph)
+
+	// sum is the summed-area table of the kernel, to find the total weight
+	// that falls outside src. sum[(y)*(size.X+1)+x] is the sum of the
+	// weights above and to the left of (x, y).
+	sw := size.X + 1
+	sum := make([]float64, sw*(size.Y+1))
+	for ky := 0; ky < size.Y; ky++ {
+		for kx := 0; kx < size.X; kx++ {
+			sum[(ky+1)*sw+kx+1] = w[ky*size.X+kx] +
+				sum[ky*sw+kx+1] + sum[(ky+1)*sw+kx] - sum[ky*sw+kx]
+		}
+	}
+	total := sum[size.Y*sw+size.X]
+
+	for y := out.Min.Y; y < out.Max.Y; y++ {
+		for x := out.Min.X; x < out.Max.X; x++ {
+			i := (y-r.Min.Y)*pw + x - r.Min.X
+			v0, v1 := rg[i]*complex(scale, 0), ba[i]*complex(scale, 0)
+			cr, cg, cb, ca := real(v0), imag(v0), real(v1), imag(v1)
+
+			// The weights outside src apply to the central pixel.
+			x0 := clampInt(bs.Min.X-x+anchor.X, 0, size.X)
+			x1 := clampInt(bs.Max.X-x+anchor.X, 0, size.X)
+			y0 := clampInt(bs.Min.Y-y+anchor.Y, 0, size.Y)
+			y1 := clampInt(bs.Max.Y-y+anchor.Y, 0, size.Y)
+			in := sum[y1*sw+x1] - sum[y0*sw+x1] - sum[y1*sw+x0] + sum[y0*sw+x0]
+			if adj := total - in; adj != 0 {
+				sr, sg, sb, sa := src.At(x, y).RGBA()
+				cr += float64(sr>>8) * adj
+				cg += float64(sg>>8) * adj
+				cb += float64(sb>>8) * adj
+				ca += float64(sa>>8) * adj
+			}
+
+			dst.set(x, y, cr, cg, cb, ca)
+		}
+	}
+}
+
+// clampInt clamps x to the range [x0, x1].
+func clampInt(x, x0, x1 int) int {
+	if x < x0 {
+		return x0
+	}
+	if x > x1 {
+		return x1
+	}
+	return x
+}
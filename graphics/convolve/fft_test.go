@@ -0,0 +1,88 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package convolve
+
+import (
+	"github.com/BurntSushi/graphics-go/graphics/graphicstest"
+	"image"
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestFFT(t *testing.T) {
+	x := make([]complex128, 16)
+	for i := range x {
+		x[i] = complex(float64(i%5), float64(i%3)-1)
+	}
+
+	// Compare with the definition of the discrete Fourier transform.
+	want := make([]complex128, len(x))
+	for k := range want {
+		for n, v := range x {
+			want[k] += v * cmplx.Rect(1, -2*math.Pi*float64(k*n)/float64(len(x)))
+		}
+	}
+	got := append([]complex128(nil), x...)
+	fft(got, false)
+	for i := range got {
+		if cmplx.Abs(got[i]-want[i]) > 1e-9 {
+			t.Fatalf("bin %d: got %v want %v", i, got[i], want[i])
+		}
+	}
+
+	fft(got, true)
+	for i := range got {
+		if v := got[i] / complex(float64(len(x)), 0); cmplx.Abs(v-x[i]) > 1e-9 {
+			t.Fatalf("inverse %d: got %v want %v", i, v, x[i])
+		}
+	}
+}
+
+func TestConvolveFFT(t *testing.T) {
+	src, err := graphicstest.LoadImage("../../testdata/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := src.Bounds()
+	inner := image.Rect(b.Min.X+20, b.Min.Y+20, b.Min.X+84, b.Min.Y+84)
+	testCases := []struct {
+		size, anchor image.Point
+		db           image.Rectangle
+	}{
+		// An asymmetric kernel with an off-center anchor, and a dst that
+		// only partly overlaps src.
+		{image.Pt(15, 9), image.Pt(3, 6), image.Rect(b.Min.X+5, b.Min.Y-7, b.Max.X+11, b.Max.Y-3)},
+		// Kernels anchored at a corner or near an edge, and a dst strictly
+		// inside src, so that the source window is not clipped by src.
+		{image.Pt(15, 15), image.Pt(0, 0), inner},
+		{image.Pt(15, 15), image.Pt(14, 2), inner},
+		{image.Pt(15, 15), image.Pt(7, 7), inner},
+	}
+	for _, tc := range testCases {
+		w := make([]float64, tc.size.X*tc.size.Y)
+		for i := range w {
+			w[i] = float64(i%7+1) / float64(4*len(w))
+		}
+		want := image.NewRGBA(tc.db)
+		convolveSpatial(rgbaWriter{want}, src, w, tc.size, tc.anchor)
+		got := image.NewRGBA(tc.db)
+		convolveFFT(rgbaWriter{got}, src, w, tc.size, tc.anchor)
+		if err := graphicstest.ImageWithinTolerance(got, want, 0x101); err != nil {
+			t.Errorf("size %v, anchor %v: %v", tc.size, tc.anchor, err)
+		}
+	}
+}
+
+func TestFFTWins(t *testing.T) {
+	r := image.Rect(0, 0, 256, 256)
+	if fftWins(r, image.Pt(3, 3)) {
+		t.Error("3x3 kernel uses the frequency domain")
+	}
+	if !fftWins(r, image.Pt(31, 31)) {
+		t.Error("31x31 kernel does not use the frequency domain")
+	}
+}
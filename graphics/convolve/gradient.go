@@ -0,0 +1,163 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package convolve
+
+import (
+	"image"
+	"math"
+)
+
+// Sobel returns the horizontal and vertical Sobel derivative kernels.
+func Sobel() (x, y *SeparableKernel) {
+	return &SeparableKernel{X: []float64{-1, 0, +1}, Y: []float64{1, 2, 1}},
+		&SeparableKernel{X: []float64{1, 2, 1}, Y: []float64{-1, 0, +1}}
+}
+
+// Scharr returns the horizontal and vertical Scharr derivative kernels,
+// which are more rotationally symmetric than Sobel.
+func Scharr() (x, y *SeparableKernel) {
+	return &SeparableKernel{X: []float64{-1, 0, +1}, Y: []float64{3, 10, 3}},
+		&SeparableKernel{X: []float64{3, 10, 3}, Y: []float64{-1, 0, +1}}
+}
+
+// Prewitt returns the horizontal and vertical Prewitt derivative kernels.
+func Prewitt() (x, y *SeparableKernel) {
+	return &SeparableKernel{X: []float64{-1, 0, +1}, Y: []float64{1, 1, 1}},
+		&SeparableKernel{X: []float64{1, 1, 1}, Y: []float64{-1, 0, +1}}
+}
+
+// Laplacian returns the 3x3 Laplacian kernel over the four nearest
+// neighbors.
+func Laplacian() Kernel {
+	return fullKernel{
+		0, +1, 0,
+		+1, -4, +1,
+		0, +1, 0,
+	}
+}
+
+// Float is an image of single float64 values, which may be negative.
+type Float struct {
+	// Pix holds the image's values. The value at (x, y) is at
+	// Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)].
+	Pix    []float64
+	Stride int
+	Rect   image.Rectangle
+}
+
+// NewFloat returns a new Float with the given bounds.
+func NewFloat(r image.Rectangle) *Float {
+	return &Float{
+		Pix:    make([]float64, r.Dx()*r.Dy()),
+		Stride: r.Dx(),
+		Rect:   r,
+	}
+}
+
+// Bounds returns the domain for which FloatAt can return non-zero values.
+func (m *Float) Bounds() image.Rectangle { return m.Rect }
+
+// FloatAt returns the value at (x, y).
+func (m *Float) FloatAt(x, y int) float64 {
+	if !image.Pt(x, y).In(m.Rect) {
+		return 0
+	}
+	return m.Pix[(y-m.Rect.Min.Y)*m.Stride+(x-m.Rect.Min.X)]
+}
+
+// SetFloat sets the value at (x, y).
+func (m *Float) SetFloat(x, y int, v float64) {
+	if !image.Pt(x, y).In(m.Rect) {
+		return
+	}
+	m.Pix[(y-m.Rect.Min.Y)*m.Stride+(x-m.Rect.Min.X)] = v
+}
+
+// Derivative is the first derivative of the luminance of an image.
+// X and Y are the signed horizontal and vertical derivatives. Mag is the
+// magnitude of the gradient, and Angle is its orientation in radians, in
+// the range [-Pi, Pi], clockwise from the positive x axis.
+type Derivative struct {
+	X, Y, Mag, Angle *Float
+}
+
+// Gradient returns the derivative of the luminance of src, in the range
+// [0, 255], using the Sobel operator.
+func Gradient(src image.Image) (*Derivative, error) {
+	if src == nil {
+		return nil, ErrNilImage
+	}
+
+	b := src.Bounds()
+	lum := luminance(src)
+	kx, ky := Sobel()
+	d := &Derivative{
+		X:     convolveFloatSep(lum, kx),
+		Y:     convolveFloatSep(lum, ky),
+		Mag:   NewFloat(b),
+		Angle: NewFloat(b),
+	}
+	for i := range d.Mag.Pix {
+		x, y := d.X.Pix[i], d.Y.Pix[i]
+		d.Mag.Pix[i] = math.Hypot(x, y)
+		d.Angle.Pix[i] = math.Atan2(y, x)
+	}
+	return d, nil
+}
+
+// luminance returns the luminance of src, in the range [0, 255].
+func luminance(src image.Image) *Float {
+	b := src.Bounds()
+	m := NewFloat(b)
+	i := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := src.At(x, y).RGBA()
+			m.Pix[i] = (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)) / 0x101
+			i++
+		}
+	}
+	return m
+}
+
+// convolveFloatSep returns the convolution of src with k. As in Convolve,
+// the weights that fall outside src are applied to the output pixel.
+func convolveFloatSep(src *Float, k *SeparableKernel) *Float {
+	_, anchor := k.Shape()
+	b := src.Rect
+	w, h := b.Dx(), b.Dy()
+
+	// buf holds the result of vertically convolving src.
+	buf := NewFloat(b)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := 0.0
+			for i, f := range k.Y {
+				sy := y + i - anchor.Y
+				if sy < 0 || sy >= h {
+					sy = y
+				}
+				v += src.Pix[sy*src.Stride+x] * f
+			}
+			buf.Pix[y*w+x] = v
+		}
+	}
+
+	dst := NewFloat(b)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := 0.0
+			for i, f := range k.X {
+				sx := x + i - anchor.X
+				if sx < 0 || sx >= w {
+					sx = x
+				}
+				v += buf.Pix[y*w+sx] * f
+			}
+			dst.Pix[y*w+x] = v
+		}
+	}
+	return dst
+}
@@ -0,0 +1,98 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package convolve
+
+import (
+	"github.com/BurntSushi/graphics-go/graphics/graphicstest"
+	"image"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestOperatorWeights(t *testing.T) {
+	sx, sy := Sobel()
+	if w, want := sx.Weights(), []float64{-1, 0, 1, -2, 0, 2, -1, 0, 1}; !reflect.DeepEqual(w, want) {
+		t.Errorf("Sobel x: got %v want %v", w, want)
+	}
+	if w, want := sy.Weights(), []float64{-1, -2, -1, 0, 0, 0, 1, 2, 1}; !reflect.DeepEqual(w, want) {
+		t.Errorf("Sobel y: got %v want %v", w, want)
+	}
+	px, _ := Prewitt()
+	if w, want := px.Weights(), []float64{-1, 0, 1, -1, 0, 1, -1, 0, 1}; !reflect.DeepEqual(w, want) {
+		t.Errorf("Prewitt x: got %v want %v", w, want)
+	}
+	cx, _ := Scharr()
+	if w, want := cx.Weights(), []float64{-3, 0, 3, -10, 0, 10, -3, 0, 3}; !reflect.DeepEqual(w, want) {
+		t.Errorf("Scharr x: got %v want %v", w, want)
+	}
+
+	// No operator responds to a flat image.
+	flat := graphicstest.MakeRGBA([]uint8{
+		0x40, 0x40, 0x40,
+		0x40, 0x40, 0x40,
+		0x40, 0x40, 0x40,
+	}, 3)
+	for _, k := range []Kernel{sx, sy, px, cx, Laplacian()} {
+		dst := image.NewRGBA(flat.Bounds())
+		if err := Convolve(dst, flat, k); err != nil {
+			t.Fatal(err)
+		}
+		if got := dst.Pix[4*4]; got != 0 {
+			t.Errorf("%v: flat got %#x want 0", k, got)
+		}
+	}
+}
+
+func TestGradient(t *testing.T) {
+	// Brightness increases by 0x10 per pixel to the right.
+	src := graphicstest.MakeRGBA([]uint8{
+		0x00, 0x10, 0x20, 0x30,
+		0x00, 0x10, 0x20, 0x30,
+		0x00, 0x10, 0x20, 0x30,
+	}, 4)
+	d, err := Gradient(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Away from the edges, the Sobel response is 8 times the slope.
+	for y := 0; y < 3; y++ {
+		for x := 1; x < 3; x++ {
+			if got := d.X.FloatAt(x, y); math.Abs(got-0x80) > 1e-9 {
+				t.Errorf("X(%d, %d): got %v want 128", x, y, got)
+			}
+			if got := d.Y.FloatAt(x, y); math.Abs(got) > 1e-9 {
+				t.Errorf("Y(%d, %d): got %v want 0", x, y, got)
+			}
+			if got := d.Mag.FloatAt(x, y); math.Abs(got-0x80) > 1e-9 {
+				t.Errorf("Mag(%d, %d): got %v want 128", x, y, got)
+			}
+			if got := d.Angle.FloatAt(x, y); math.Abs(got) > 1e-9 {
+				t.Errorf("Angle(%d, %d): got %v want 0", x, y, got)
+			}
+		}
+	}
+
+	// Reversing the ramp gives a negative derivative.
+	flipped := graphicstest.MakeRGBA([]uint8{
+		0x30, 0x20, 0x10, 0x00,
+		0x30, 0x20, 0x10, 0x00,
+	}, 4)
+	d, err = Gradient(flipped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.X.FloatAt(1, 0); math.Abs(got+0x80) > 1e-9 {
+		t.Errorf("flipped X: got %v want -128", got)
+	}
+	if got := d.Angle.FloatAt(1, 0); math.Abs(got-math.Pi) > 1e-9 {
+		t.Errorf("flipped Angle: got %v want Pi", got)
+	}
+
+	if _, err := Gradient(nil); err == nil {
+		t.Error("nil src: got nil error")
+	}
+}
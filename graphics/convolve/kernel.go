@@ -0,0 +1,229 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package convolve
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+)
+
+// Scale returns k with each weight multiplied by f. Separable kernels stay
+// separable.
+func Scale(k Kernel, f float64) (Kernel, error) {
+	if k == nil {
+		return nil, ErrNilKernel
+	}
+	if sk, ok := k.(*SeparableKernel); ok {
+		x := make([]float64, len(sk.X))
+		for i, v := range sk.X {
+			x[i] = v * f
+		}
+		return &SeparableKernel{X: x, Y: copyWeights(sk.Y), Anchor: copyAnchor(sk.Anchor)}, nil
+	}
+	w, size, anchor, err := kernelShape(k)
+	if err != nil {
+		return nil, err
+	}
+	s := make([]float64, len(w))
+	for i, v := range w {
+		s[i] = v * f
+	}
+	return &rectKernel{s, size, anchor}, nil
+}
+
+// Normalize returns k scaled so that its weights add up to sum. A kernel
+// whose weights add up to 1 keeps the brightness of the image.
+func Normalize(k Kernel, sum float64) (Kernel, error) {
+	if k == nil {
+		return nil, ErrNilKernel
+	}
+	total := 0.0
+	for _, v := range k.Weights() {
+		total += v
+	}
+	if total == 0 {
+		return nil, errors.New("graphics: kernel weights add up to zero")
+	}
+	return Scale(k, sum/total)
+}
+
+// Add returns the kernel whose weights are the sums of those of a and b,
+// lined up at their anchors. It is as large as needed to hold both.
+func Add(a, b Kernel) (Kernel, error) {
+	if a == nil || b == nil {
+		return nil, ErrNilKernel
+	}
+	wa, sa, aa, err := kernelShape(a)
+	if err != nil {
+		return nil, err
+	}
+	wb, sb, ab, err := kernelShape(b)
+	if err != nil {
+		return nil, err
+	}
+
+	// The result reaches as far from the anchor as either kernel does.
+	anchor := image.Pt(maxInt(aa.X, ab.X), maxInt(aa.Y, ab.Y))
+	size := anchor.Add(image.Pt(
+		maxInt(sa.X-aa.X, sb.X-ab.X),
+		maxInt(sa.Y-aa.Y, sb.Y-ab.Y),
+	))
+	w := make([]float64, size.X*size.Y)
+	for _, k := range []struct {
+		w            []float64
+		size, anchor image.Point
+	}{{wa, sa, aa}, {wb, sb, ab}} {
+		d := anchor.Sub(k.anchor)
+		for y := 0; y < k.size.Y; y++ {
+			for x := 0; x < k.size.X; x++ {
+				w[(y+d.Y)*size.X+x+d.X] += k.w[y*k.size.X+x]
+			}
+		}
+	}
+	return &rectKernel{w, size, anchor}, nil
+}
+
+// Compose returns the kernel that is the same as convolving with a and
+// then with b, away from the edges of the image. Two separable kernels
+// compose to a separable kernel.
+func Compose(a, b Kernel) (Kernel, error) {
+	if a == nil || b == nil {
+		return nil, ErrNilKernel
+	}
+	wa, sizeA, aa, err := kernelShape(a)
+	if err != nil {
+		return nil, err
+	}
+	wb, sizeB, ab, err := kernelShape(b)
+	if err != nil {
+		return nil, err
+	}
+	anchor := aa.Add(ab)
+	sa, ok0 := a.(*SeparableKernel)
+	sb, ok1 := b.(*SeparableKernel)
+	if ok0 && ok1 {
+		return &SeparableKernel{
+			X:      compose1(sa.X, sb.X),
+			Y:      compose1(sa.Y, sb.Y),
+			Anchor: &anchor,
+		}, nil
+	}
+
+	size := sizeA.Add(sizeB).Sub(image.Pt(1, 1))
+	w := make([]float64, size.X*size.Y)
+	for ya := 0; ya < sizeA.Y; ya++ {
+		for xa := 0; xa < sizeA.X; xa++ {
+			fa := wa[ya*sizeA.X+xa]
+			if fa == 0 {
+				continue
+			}
+			for yb := 0; yb < sizeB.Y; yb++ {
+				for xb := 0; xb < sizeB.X; xb++ {
+					w[(ya+yb)*size.X+xa+xb] += fa * wb[yb*sizeB.X+xb]
+				}
+			}
+		}
+	}
+	return &rectKernel{w, size, anchor}, nil
+}
+
+// compose1 returns the full one dimensional convolution of a and b.
+func compose1(a, b []float64) []float64 {
+	w := make([]float64, len(a)+len(b)-1)
+	for i, fa := range a {
+		for j, fb := range b {
+			w[i+j] += fa * fb
+		}
+	}
+	return w
+}
+
+// Transpose returns k reflected in its main diagonal, so that horizontal
+// kernels become vertical ones. Separable kernels stay separable.
+func Transpose(k Kernel) (Kernel, error) {
+	if k == nil {
+		return nil, ErrNilKernel
+	}
+	if sk, ok := k.(*SeparableKernel); ok {
+		t := &SeparableKernel{X: copyWeights(sk.Y), Y: copyWeights(sk.X)}
+		if sk.Anchor != nil {
+			t.Anchor = &image.Point{sk.Anchor.Y, sk.Anchor.X}
+		}
+		return t, nil
+	}
+	w, size, anchor, err := kernelShape(k)
+	if err != nil {
+		return nil, err
+	}
+	t := make([]float64, len(w))
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			t[x*size.Y+y] = w[y*size.X+x]
+		}
+	}
+	return &rectKernel{t, image.Pt(size.Y, size.X), image.Pt(anchor.Y, anchor.X)}, nil
+}
+
+// ParseKernel parses a kernel from a matrix of weights in text, such as
+// "1 2 1; 2 4 2; 1 2 1". Rows are separated by semicolons or newlines, and
+// the weights in a row by spaces or commas. All rows must be the same
+// length. The anchor is the middle weight, as for NewKernel.
+func ParseKernel(s string) (RectKernel, error) {
+	rows := strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == '\n' })
+	var w []float64
+	width, height := 0, 0
+	for _, row := range rows {
+		fields := strings.FieldsFunc(row, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\r'
+		})
+		if len(fields) == 0 {
+			continue
+		}
+		if height == 0 {
+			width = len(fields)
+		} else if len(fields) != width {
+			return nil, fmt.Errorf("graphics: kernel row %d has %d weights, not %d", height+1, len(fields), width)
+		}
+		for _, f := range fields {
+			v, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return nil, fmt.Errorf("graphics: bad kernel weight %q", f)
+			}
+			w = append(w, v)
+		}
+		height++
+	}
+	size := image.Pt(width, height)
+	return NewRectKernel(w, size, image.Pt(width/2, height/2))
+}
+
+func copyWeights(w []float64) []float64 {
+	return append([]float64(nil), w...)
+}
+
+func copyAnchor(p *image.Point) *image.Point {
+	if p == nil {
+		return nil
+	}
+	q := *p
+	return &q
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,147 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package convolve
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+// shapeOf returns the weights, size and anchor of k, failing t on error.
+func shapeOf(t *testing.T, k Kernel) ([]float64, image.Point, image.Point) {
+	w, size, anchor, err := kernelShape(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w, size, anchor
+}
+
+func TestParseKernel(t *testing.T) {
+	k, err := ParseKernel("1 2 1; 2, 4, 2\n1 2 1;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, size, anchor := shapeOf(t, k)
+	want := []float64{1, 2, 1, 2, 4, 2, 1, 2, 1}
+	if !reflect.DeepEqual(w, want) || size != image.Pt(3, 3) || anchor != image.Pt(1, 1) {
+		t.Errorf("got %v %v %v", w, size, anchor)
+	}
+
+	for _, s := range []string{"", "1 2; 3", "1 x 1"} {
+		if _, err := ParseKernel(s); err == nil {
+			t.Errorf("%q: got nil error", s)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	k, err := ParseKernel("1 2 1; 2 4 2; 1 2 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := Normalize(k, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, _, _ := shapeOf(t, n)
+	if w[4] != 0.25 || w[0] != 1.0/16 {
+		t.Errorf("got %v", w)
+	}
+	if _, err := Normalize(Laplacian(), 1); err == nil {
+		t.Error("zero sum: got nil error")
+	}
+
+	sk, err := Normalize(&SeparableKernel{X: []float64{1, 2, 1}, Y: []float64{1, 2, 1}}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sk.(*SeparableKernel); !ok {
+		t.Errorf("separable kernel normalized to %T", sk)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	a, err := NewRectKernel([]float64{1, 2, 3}, image.Pt(3, 1), image.Pt(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewKernel([]float64{
+		0, 1, 0,
+		1, 1, 1,
+		0, 1, 0,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := Add(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, size, anchor := shapeOf(t, sum)
+	want := []float64{
+		0, 1, 0, 0,
+		1, 2, 3, 3,
+		0, 1, 0, 0,
+	}
+	if !reflect.DeepEqual(w, want) || size != image.Pt(4, 3) || anchor != image.Pt(1, 1) {
+		t.Errorf("got %v %v %v", w, size, anchor)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	// Two box filters make a triangle filter.
+	box := &SeparableKernel{X: []float64{1, 1}, Y: []float64{1}, Anchor: &image.Point{}}
+	c, err := Compose(box, box)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, size, anchor := shapeOf(t, c)
+	if !reflect.DeepEqual(w, []float64{1, 2, 1}) || size != image.Pt(3, 1) || anchor != image.Pt(0, 0) {
+		t.Errorf("separable: got %v %v %v", w, size, anchor)
+	}
+
+	full, err := NewRectKernel([]float64{1, 1}, image.Pt(2, 1), image.Pt(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vert, err := Transpose(full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err = Compose(full, vert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, size, anchor = shapeOf(t, c)
+	if !reflect.DeepEqual(w, []float64{1, 1, 1, 1}) || size != image.Pt(2, 2) || anchor != image.Pt(0, 0) {
+		t.Errorf("full: got %v %v %v", w, size, anchor)
+	}
+}
+
+func TestTranspose(t *testing.T) {
+	k, err := NewRectKernel([]float64{1, 2, 3, 4, 5, 6}, image.Pt(3, 2), image.Pt(2, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk, err := Transpose(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, size, anchor := shapeOf(t, tk)
+	if !reflect.DeepEqual(w, []float64{1, 4, 2, 5, 3, 6}) || size != image.Pt(2, 3) || anchor != image.Pt(0, 2) {
+		t.Errorf("got %v %v %v", w, size, anchor)
+	}
+
+	sx, _ := Sobel()
+	st, err := Transpose(sx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, sy := Sobel()
+	if !reflect.DeepEqual(st.Weights(), sy.Weights()) {
+		t.Errorf("Sobel: got %v want %v", st.Weights(), sy.Weights())
+	}
+}
@@ -0,0 +1,360 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package convolve
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+)
+
+// Element is a structuring element for morphology. Mask holds Size.X by
+// Size.Y values in row major order, true for the pixels that the element
+// covers. Anchor is the position in Mask of the output pixel.
+type Element struct {
+	Mask   []bool
+	Size   image.Point
+	Anchor image.Point
+}
+
+// NewElement returns a structuring element with the given mask.
+func NewElement(mask []bool, size, anchor image.Point) (*Element, error) {
+	if err := checkShape(len(mask), size, anchor); err != nil {
+		return nil, err
+	}
+	return &Element{mask, size, anchor}, nil
+}
+
+// RectElement returns a structuring element covering a rectangle of the
+// given size, anchored at its middle.
+func RectElement(size image.Point) *Element {
+	return newElement(size, func(x, y int) bool { return true })
+}
+
+// EllipseElement returns a structuring element covering the pixels whose
+// centers lie within the ellipse inscribed in a rectangle of the given
+// size, anchored at its middle.
+func EllipseElement(size image.Point) *Element {
+	rx, ry := float64(size.X)/2, float64(size.Y)/2
+	return newElement(size, func(x, y int) bool {
+		dx := (float64(x) + 0.5 - rx) / rx
+		dy := (float64(y) + 0.5 - ry) / ry
+		return dx*dx+dy*dy <= 1
+	})
+}
+
+// CrossElement returns a structuring element covering the middle row and
+// the middle column of a rectangle of the given size, anchored at its
+// middle.
+func CrossElement(size image.Point) *Element {
+	return newElement(size, func(x, y int) bool { return x == size.X/2 || y == size.Y/2 })
+}
+
+func newElement(size image.Point, in func(x, y int) bool) *Element {
+	if size.X < 1 || size.Y < 1 {
+		size = image.Pt(1, 1)
+	}
+	e := &Element{
+		Mask:   make([]bool, size.X*size.Y),
+		Size:   size,
+		Anchor: image.Pt(size.X/2, size.Y/2),
+	}
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			e.Mask[y*size.X+x] = in(x, y)
+		}
+	}
+	return e
+}
+
+// isRect reports whether e covers the whole of its rectangle.
+func (e *Element) isRect() bool {
+	for _, m := range e.Mask {
+		if !m {
+			return false
+		}
+	}
+	return true
+}
+
+// reflect returns e rotated by half a turn about its anchor.
+func (e *Element) reflect() *Element {
+	n := len(e.Mask)
+	r := &Element{
+		Mask:   make([]bool, n),
+		Size:   e.Size,
+		Anchor: e.Size.Sub(e.Anchor).Sub(image.Pt(1, 1)),
+	}
+	for i, m := range e.Mask {
+		r.Mask[n-1-i] = m
+	}
+	return r
+}
+
+// Erode produces dst by taking, for each channel of each pixel, the least
+// value under e. It shrinks bright regions and removes small bright
+// details. Pixels beyond the edges of src are ignored.
+//
+// Elements that cover their whole rectangle take a time that does not
+// depend on their size. See M. van Herk. A fast algorithm for local
+// minimum and maximum filters on rectangular and octagonal kernels,
+// Pattern Recognition Letters, 1992.
+func Erode(dst draw.Image, src image.Image, e *Element) error {
+	return morph(dst, src, e, false)
+}
+
+// Dilate produces dst by taking, for each channel of each pixel, the
+// greatest value under e reflected about its anchor. It grows bright
+// regions and fills small dark details.
+func Dilate(dst draw.Image, src image.Image, e *Element) error {
+	if e == nil {
+		return errNilElement
+	}
+	return morph(dst, src, e.reflect(), true)
+}
+
+// Open produces dst by eroding src and then dilating the result. It removes
+// bright details smaller than e.
+func Open(dst draw.Image, src image.Image, e *Element) error {
+	return morph2(dst, src, e, Erode, Dilate)
+}
+
+// Close produces dst by dilating src and then eroding the result. It fills
+// dark details smaller than e.
+func Close(dst draw.Image, src image.Image, e *Element) error {
+	return morph2(dst, src, e, Dilate, Erode)
+}
+
+// MorphGradient produces dst as the difference between the dilation and
+// the erosion of src, which outlines the edges of regions. The alpha of
+// dst is that of src.
+func MorphGradient(dst draw.Image, src image.Image, e *Element) error {
+	if dst == nil || src == nil {
+		return ErrNilImage
+	}
+	b := dst.Bounds()
+	d, er := image.NewRGBA(b), image.NewRGBA(b)
+	if err := Dilate(d, src, e); err != nil {
+		return err
+	}
+	if err := Erode(er, src, e); err != nil {
+		return err
+	}
+	return morphDiff(dst, src, d, er)
+}
+
+// TopHat produces dst as the difference between src and its opening, which
+// keeps the bright details smaller than e. The alpha of dst is that of src.
+func TopHat(dst draw.Image, src image.Image, e *Element) error {
+	if dst == nil || src == nil {
+		return ErrNilImage
+	}
+	b := dst.Bounds()
+	m, o := image.NewRGBA(b), image.NewRGBA(b)
+	draw.Draw(m, b, src, b.Min, draw.Src)
+	if err := Open(o, src, e); err != nil {
+		return err
+	}
+	return morphDiff(dst, src, m, o)
+}
+
+var errNilElement = errors.New("graphics: structuring element is nil")
+
+// morph2 applies f and then g to src.
+func morph2(dst draw.Image, src image.Image, e *Element, f, g func(draw.Image, image.Image, *Element) error) error {
+	if dst == nil || src == nil {
+		return ErrNilImage
+	}
+	tmp := image.NewRGBA(dst.Bounds())
+	if err := f(tmp, src, e); err != nil {
+		return err
+	}
+	return g(dst, tmp, e)
+}
+
+// morphDiff produces dst with the color of a less that of b, and the alpha
+// of src. a and b have the bounds of dst.
+func morphDiff(dst draw.Image, src image.Image, a, b *image.RGBA) error {
+	r := dst.Bounds()
+	m := image.NewRGBA(r)
+	draw.Draw(m, r, src, r.Min, draw.Src)
+	for i := 0; i < len(m.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			v := 0
+			if a.Pix[i+c] > b.Pix[i+c] {
+				v = int(a.Pix[i+c] - b.Pix[i+c])
+			}
+			if v > int(m.Pix[i+3]) {
+				v = int(m.Pix[i+3])
+			}
+			m.Pix[i+c] = uint8(v)
+		}
+	}
+	draw.Draw(dst, r, m, r.Min, draw.Src)
+	return nil
+}
+
+// morph produces dst by taking the least, or if max the greatest, value of
+// each channel under e.
+func morph(dst draw.Image, src image.Image, e *Element, max bool) error {
+	if dst == nil || src == nil {
+		return ErrNilImage
+	}
+	if e == nil {
+		return errNilElement
+	}
+	if err := checkShape(len(e.Mask), e.Size, e.Anchor); err != nil {
+		return err
+	}
+
+	b := dst.Bounds()
+	if b.Empty() {
+		return nil
+	}
+	m := image.NewRGBA(b)
+	draw.Draw(m, b, src, b.Min, draw.Src)
+
+	// The least and greatest of premultiplied colors are still no more
+	// than alpha, so the channels can be taken one by one.
+	w, h := b.Dx(), b.Dy()
+	plane := make([]uint8, w*h)
+	for c := 0; c < 4; c++ {
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				plane[y*w+x] = m.Pix[y*m.Stride+x*4+c]
+			}
+		}
+		out := morphPlane(plane, w, h, e, max)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				m.Pix[y*m.Stride+x*4+c] = out[y*w+x]
+			}
+		}
+	}
+
+	draw.Draw(dst, b, m, b.Min, draw.Src)
+	return nil
+}
+
+// morphPlane returns the least, or if max the greatest, of the values of p
+// under e. p is w wide and h high.
+func morphPlane(p []uint8, w, h int, e *Element, max bool) []uint8 {
+	if e.isRect() {
+		// The rectangle is separable into a row and a column.
+		out := make([]uint8, w*h)
+		for y := 0; y < h; y++ {
+			vanHerk(out[y*w:(y+1)*w], p[y*w:(y+1)*w], -e.Anchor.X, e.Size.X-1-e.Anchor.X, max)
+		}
+		col, res := make([]uint8, h), make([]uint8, h)
+		for x := 0; x < w; x++ {
+			for y := range col {
+				col[y] = out[y*w+x]
+			}
+			vanHerk(res, col, -e.Anchor.Y, e.Size.Y-1-e.Anchor.Y, max)
+			for y, v := range res {
+				out[y*w+x] = v
+			}
+		}
+		return out
+	}
+
+	// Otherwise, each run of covered pixels in a row of e is a rectangle
+	// one pixel high. runs caches the result of each distinct run.
+	out := make([]uint8, w*h)
+	for i := range out {
+		out[i] = morphIdentity(max)
+	}
+	runs := map[[2]int][]uint8{}
+	for ky := 0; ky < e.Size.Y; ky++ {
+		row := e.Mask[ky*e.Size.X : (ky+1)*e.Size.X]
+		for x0 := 0; x0 < len(row); x0++ {
+			if !row[x0] {
+				continue
+			}
+			x1 := x0
+			for x1+1 < len(row) && row[x1+1] {
+				x1++
+			}
+			run := [2]int{x0, x1}
+			hp, ok := runs[run]
+			if !ok {
+				hp = make([]uint8, w*h)
+				for y := 0; y < h; y++ {
+					vanHerk(hp[y*w:(y+1)*w], p[y*w:(y+1)*w], x0-e.Anchor.X, x1-e.Anchor.X, max)
+				}
+				runs[run] = hp
+			}
+			dy := ky - e.Anchor.Y
+			for y := 0; y < h; y++ {
+				sy := y + dy
+				if sy < 0 || sy >= h {
+					continue
+				}
+				o, s := out[y*w:(y+1)*w], hp[sy*w:(sy+1)*w]
+				for x, v := range s {
+					o[x] = morphPick(o[x], v, max)
+				}
+			}
+			x0 = x1
+		}
+	}
+	return out
+}
+
+// vanHerk sets dst[i] to the least, or if max the greatest, of src[i+lo]
+// through src[i+hi], ignoring those outside src, with a constant number of
+// comparisons per value.
+func vanHerk(dst, src []uint8, lo, hi int, max bool) {
+	n, size := len(src), hi-lo+1
+	id := morphIdentity(max)
+
+	// q[j] is src[j+lo], so that the window for i starts at q[i].
+	m := n + size - 1
+	q := make([]uint8, m)
+	for j := range q {
+		if s := j + lo; s >= 0 && s < n {
+			q[j] = src[s]
+		} else {
+			q[j] = id
+		}
+	}
+
+	// Within each block of size values, g accumulates from the left and h
+	// from the right. Each window spans at most two blocks.
+	g, h := make([]uint8, m), make([]uint8, m)
+	for j := range q {
+		if j%size == 0 {
+			g[j] = q[j]
+		} else {
+			g[j] = morphPick(g[j-1], q[j], max)
+		}
+	}
+	for j := m - 1; j >= 0; j-- {
+		if j == m-1 || (j+1)%size == 0 {
+			h[j] = q[j]
+		} else {
+			h[j] = morphPick(h[j+1], q[j], max)
+		}
+	}
+	for i := range dst {
+		dst[i] = morphPick(h[i], g[i+size-1], max)
+	}
+}
+
+// morphIdentity returns the value that morphPick never picks over another.
+func morphIdentity(max bool) uint8 {
+	if max {
+		return 0
+	}
+	return 0xff
+}
+
+// morphPick returns the lesser, or if max the greater, of a and b.
+func morphPick(a, b uint8, max bool) uint8 {
+	if (b > a) == max {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,186 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package convolve
+
+import (
+	"github.com/BurntSushi/graphics-go/graphics/graphicstest"
+	"image"
+	"testing"
+)
+
+// morphSlow is morphPlane, computed directly.
+func morphSlow(p []uint8, w, h int, e *Element, max bool) []uint8 {
+	out := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := morphIdentity(max)
+			for ky := 0; ky < e.Size.Y; ky++ {
+				for kx := 0; kx < e.Size.X; kx++ {
+					sx, sy := x+kx-e.Anchor.X, y+ky-e.Anchor.Y
+					if !e.Mask[ky*e.Size.X+kx] || sx < 0 || sx >= w || sy < 0 || sy >= h {
+						continue
+					}
+					v = morphPick(v, p[sy*w+sx], max)
+				}
+			}
+			out[y*w+x] = v
+		}
+	}
+	return out
+}
+
+func TestMorphPlane(t *testing.T) {
+	w, h := 23, 17
+	p := make([]uint8, w*h)
+	for i := range p {
+		p[i] = uint8(i * 7919 % 251)
+	}
+	asym, err := NewElement([]bool{
+		true, false, true, true,
+		false, true, true, false,
+	}, image.Pt(4, 2), image.Pt(3, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	corner := RectElement(image.Pt(5, 3))
+	corner.Anchor = image.Pt(0, 2)
+	elements := []*Element{
+		RectElement(image.Pt(1, 1)),
+		RectElement(image.Pt(5, 3)),
+		corner,
+		EllipseElement(image.Pt(7, 5)),
+		CrossElement(image.Pt(5, 5)),
+		asym,
+	}
+	for i, e := range elements {
+		for _, max := range []bool{false, true} {
+			got := morphPlane(p, w, h, e, max)
+			want := morphSlow(p, w, h, e, max)
+			for j := range got {
+				if got[j] != want[j] {
+					t.Errorf("element %d, max %t: (%d, %d): got %d want %d", i, max, j%w, j/w, got[j], want[j])
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestElements(t *testing.T) {
+	e := EllipseElement(image.Pt(5, 5))
+	want := []bool{
+		false, true, true, true, false,
+		true, true, true, true, true,
+		true, true, true, true, true,
+		true, true, true, true, true,
+		false, true, true, true, false,
+	}
+	for i := range want {
+		if e.Mask[i] != want[i] {
+			t.Fatalf("ellipse: got %v want %v", e.Mask, want)
+		}
+	}
+	c := CrossElement(image.Pt(3, 3))
+	if n := countMask(c.Mask); n != 5 {
+		t.Errorf("cross: %d pixels, want 5", n)
+	}
+}
+
+func countMask(mask []bool) int {
+	n := 0
+	for _, m := range mask {
+		if m {
+			n++
+		}
+	}
+	return n
+}
+
+func TestOpenClose(t *testing.T) {
+	// A 3x3 square, a lone pixel and a hole in the square.
+	src := graphicstest.MakeRGBA([]uint8{
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00,
+		0x00, 0xff, 0x00, 0xff, 0x00, 0xff, 0x00,
+		0x00, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}, 7)
+	e := CrossElement(image.Pt(3, 3))
+	b := src.Bounds()
+
+	// Closing fills the hole, and keeps the lone pixel.
+	dst := image.NewRGBA(b)
+	if err := Close(dst, src, e); err != nil {
+		t.Fatal(err)
+	}
+	if v := dst.Pix[dst.PixOffset(2, 2)]; v != 0xff {
+		t.Errorf("close: hole is %#x", v)
+	}
+	if v := dst.Pix[dst.PixOffset(5, 2)]; v != 0xff {
+		t.Errorf("close: lone pixel is %#x", v)
+	}
+
+	// Opening removes the lone pixel.
+	if err := Open(dst, src, RectElement(image.Pt(1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	if err := graphicstest.ImageWithinTolerance(dst, src, 0); err != nil {
+		t.Errorf("open with a point: %v", err)
+	}
+	if err := Open(dst, src, e); err != nil {
+		t.Fatal(err)
+	}
+	if v := dst.Pix[dst.PixOffset(5, 2)]; v != 0 {
+		t.Errorf("open: lone pixel is %#x", v)
+	}
+
+	// The top-hat keeps the lone pixel. Within the square, it also keeps
+	// the corners, which the cross does not fit, so the square is skipped.
+	if err := TopHat(dst, src, e); err != nil {
+		t.Fatal(err)
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			want := uint8(0)
+			if x == 5 && y == 2 {
+				want = 0xff
+			}
+			if v := dst.Pix[dst.PixOffset(x, y)]; v != want && !(x >= 1 && x <= 3 && y >= 1 && y <= 3) {
+				t.Errorf("top-hat (%d, %d): got %#x want %#x", x, y, v, want)
+			}
+		}
+	}
+}
+
+func TestMorphGradient(t *testing.T) {
+	src := graphicstest.MakeRGBA([]uint8{
+		0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x80, 0x80, 0x80, 0x00,
+		0x00, 0x80, 0x80, 0x80, 0x00,
+		0x00, 0x80, 0x80, 0x80, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00,
+	}, 5)
+	dst := image.NewRGBA(src.Bounds())
+	if err := MorphGradient(dst, src, RectElement(image.Pt(3, 3))); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the middle pixel is not near an edge.
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 5; x++ {
+			want := uint8(0x80)
+			if x == 2 && y == 2 {
+				want = 0
+			}
+			if v := dst.Pix[dst.PixOffset(x, y)]; v != want {
+				t.Errorf("(%d, %d): got %#x want %#x", x, y, v, want)
+			}
+		}
+	}
+
+	if err := Erode(dst, src, nil); err == nil {
+		t.Error("nil element: got nil error")
+	}
+}
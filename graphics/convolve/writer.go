@@ -0,0 +1,100 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package convolve
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// pixelWriter stores the results of a convolution in an image.
+type pixelWriter interface {
+	Bounds() image.Rectangle
+	// set stores the premultiplied color (r, g, b, a), with channels in
+	// the range [0, 255], at (x, y). Out of range values are clamped.
+	set(x, y int, r, g, b, a float64)
+}
+
+// newPixelWriter returns a pixelWriter for dst, and whether it writes to
+// dst directly. If not, it writes to a new image.RGBA with the same
+// bounds, which must then be drawn onto dst.
+func newPixelWriter(dst draw.Image) (w pixelWriter, direct bool) {
+	switch dst := dst.(type) {
+	case *image.RGBA:
+		return rgbaWriter{dst}, true
+	case *image.NRGBA:
+		return nrgbaWriter{dst}, true
+	case *image.RGBA64:
+		return rgba64Writer{dst}, true
+	case *image.Gray:
+		return grayWriter{dst}, true
+	}
+	return rgbaWriter{image.NewRGBA(dst.Bounds())}, false
+}
+
+type rgbaWriter struct{ *image.RGBA }
+
+func (m rgbaWriter) set(x, y int, r, g, b, a float64) {
+	off := m.PixOffset(x, y)
+	m.Pix[off+0] = uint8(clamp(r+0.5, 0, 0xff))
+	m.Pix[off+1] = uint8(clamp(g+0.5, 0, 0xff))
+	m.Pix[off+2] = uint8(clamp(b+0.5, 0, 0xff))
+	m.Pix[off+3] = uint8(clamp(a+0.5, 0, 0xff))
+}
+
+type nrgbaWriter struct{ *image.NRGBA }
+
+func (m nrgbaWriter) set(x, y int, r, g, b, a float64) {
+	off := m.PixOffset(x, y)
+	a = clamp(a, 0, 0xff)
+	if a == 0 {
+		m.Pix[off+0], m.Pix[off+1], m.Pix[off+2], m.Pix[off+3] = 0, 0, 0, 0
+		return
+	}
+	// Divide out alpha.
+	f := 0xff / a
+	m.Pix[off+0] = uint8(clamp(r*f+0.5, 0, 0xff))
+	m.Pix[off+1] = uint8(clamp(g*f+0.5, 0, 0xff))
+	m.Pix[off+2] = uint8(clamp(b*f+0.5, 0, 0xff))
+	m.Pix[off+3] = uint8(a + 0.5)
+}
+
+type rgba64Writer struct{ *image.RGBA64 }
+
+func (m rgba64Writer) set(x, y int, r, g, b, a float64) {
+	off := m.PixOffset(x, y)
+	for i, v := range [4]float64{r, g, b, a} {
+		c := uint16(clamp(v*0x101+0.5, 0, 0xffff))
+		m.Pix[off+2*i+0] = uint8(c >> 8)
+		m.Pix[off+2*i+1] = uint8(c)
+	}
+}
+
+type grayWriter struct{ *image.Gray }
+
+func (m grayWriter) set(x, y int, r, g, b, a float64) {
+	// The same weights as color.GrayModel, which ignores alpha.
+	l := 0.299*r + 0.587*g + 0.114*b
+	m.Pix[m.PixOffset(x, y)] = uint8(clamp(l+0.5, 0, 0xff))
+}
+
+// affineWriter maps the red, green and blue values by v*scale + bias, after
+// taking the absolute value if abs, and leaves alpha as it is.
+type affineWriter struct {
+	pixelWriter
+	scale, bias float64
+	abs         bool
+}
+
+func (m affineWriter) set(x, y int, r, g, b, a float64) {
+	if m.abs {
+		r, g, b = math.Abs(r), math.Abs(g), math.Abs(b)
+	}
+	r = r*m.scale + m.bias
+	g = g*m.scale + m.bias
+	b = b*m.scale + m.bias
+	m.pixelWriter.set(x, y, r, g, b, a)
+}
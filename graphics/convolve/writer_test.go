@@ -0,0 +1,43 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package convolve
+
+import (
+	"github.com/BurntSushi/graphics-go/graphics/graphicstest"
+	"image"
+	"image/draw"
+	"testing"
+)
+
+func TestConvolveWriters(t *testing.T) {
+	src, err := graphicstest.LoadImage("../../testdata/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := src.Bounds()
+	k := &SeparableKernel{X: []float64{0.25, 0.5, 0.25}, Y: []float64{0.25, 0.5, 0.25}}
+	rgba := image.NewRGBA(b)
+	if err := Convolve(rgba, src, k); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each type of dst matches the RGBA result, converted to that type.
+	news := []func(image.Rectangle) draw.Image{
+		func(r image.Rectangle) draw.Image { return image.NewNRGBA(r) },
+		func(r image.Rectangle) draw.Image { return image.NewRGBA64(r) },
+		func(r image.Rectangle) draw.Image { return image.NewGray(r) },
+		func(r image.Rectangle) draw.Image { return image.NewGray16(r) },
+	}
+	for _, newImage := range news {
+		got, want := newImage(b), newImage(b)
+		if err := Convolve(got, src, k); err != nil {
+			t.Fatal(err)
+		}
+		draw.Draw(want, b, rgba, b.Min, draw.Src)
+		if err := graphicstest.ImageWithinTolerance(got, want, 0x202); err != nil {
+			t.Errorf("%T: %v", got, err)
+		}
+	}
+}
@@ -9,27 +9,47 @@ import (
 	"math"
 )
 
-// Feature is a Haar-like feature.
+// Feature is a Haar-like feature. If Tilted, Rect is rotated by 45 degrees
+// clockwise about its top left corner, which becomes the top corner, as in
+// OpenCV.
 type Feature struct {
 	Rect   image.Rectangle
 	Weight float64
+	Tilted bool
 }
 
-// Classifier is a set of features with a threshold.
+// Classifier is a set of features with a threshold. If the normalized sum
+// of the features is below the threshold, the classifier's value is Left,
+// or if LeftNode is not nil, the value of LeftNode. Otherwise it is Right,
+// or the value of RightNode. The nodes make a decision tree.
+//
+// If Subset is not nil, the classifier uses a local binary pattern instead.
+// The pattern compares the sum of the middle block of a 3x3 grid of blocks,
+// the first of which is Feature[0].Rect, with the sums of the other blocks,
+// giving a value in [0, 256). The classifier goes left if that bit of
+// Subset is set, and right otherwise. Threshold and Weight are unused.
 type Classifier struct {
 	Feature   []Feature
 	Threshold float64
 	Left      float64
 	Right     float64
+	LeftNode  *Classifier
+	RightNode *Classifier
+	Subset    []uint32
 }
 
-// CascadeStage is a cascade of classifiers.
+// CascadeStage is a cascade of classifiers. A window passes the stage if
+// the sum of the classifiers is at least the threshold. If Branches is not
+// nil, the window must then also pass the stages of one of the branches,
+// which are tried in order.
 type CascadeStage struct {
 	Classifier []Classifier
 	Threshold  float64
+	Branches   [][]CascadeStage
 }
 
-// Cascade is a degenerate tree of Haar-like classifiers.
+// Cascade is a degenerate tree of Haar-like classifiers. A window matches
+// if it passes each stage in turn. Stages with Branches make a tree.
 type Cascade struct {
 	Stage []CascadeStage
 	Size  image.Point
@@ -37,14 +57,15 @@ type Cascade struct {
 
 // Match returns true if the full image is classified as an object.
 func (c *Cascade) Match(m image.Image) bool {
-	return c.classify(newWindow(m))
+	return c.classify(c.newWindow(m))
 }
 
 // Find returns a set of areas of m that match the feature cascade c.
+// An object usually matches at several nearby positions and scales; Detect
+// merges them.
 func (c *Cascade) Find(m image.Image) []image.Rectangle {
-	// TODO(crawshaw): Consider de-duping strategies.
 	matches := []image.Rectangle{}
-	w := newWindow(m)
+	w := c.newWindow(m)
 
 	b := m.Bounds()
 	origScale := c.Size
@@ -66,6 +87,7 @@ func (c *Cascade) Find(m image.Image) []image.Rectangle {
 type window struct {
 	mi      *integral
 	miSq    *integral
+	mt      *integral // tilted, if needed
 	rect    image.Rectangle
 	invArea float64
 	stdDev  float64
@@ -92,10 +114,57 @@ func newWindow(m image.Image) *window {
 	return res
 }
 
+// newWindow returns a window on m, with the tilted integral if c has
+// tilted features.
+func (c *Cascade) newWindow(m image.Image) *window {
+	w := newWindow(m)
+	if c.tilted() {
+		w.mt = newTiltedIntegral(m)
+	}
+	return w
+}
+
+// tilted reports whether c has any tilted features.
+func (c *Cascade) tilted() bool {
+	return stagesTilted(c.Stage)
+}
+
+// stagesTilted reports whether stages or their branches have any tilted
+// features.
+func stagesTilted(stages []CascadeStage) bool {
+	for _, s := range stages {
+		for i := range s.Classifier {
+			if s.Classifier[i].tilted() {
+				return true
+			}
+		}
+		for _, b := range s.Branches {
+			if stagesTilted(b) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tilted reports whether c or its nodes have any tilted features.
+func (c *Classifier) tilted() bool {
+	if c == nil {
+		return false
+	}
+	for _, f := range c.Feature {
+		if f.Tilted {
+			return true
+		}
+	}
+	return c.LeftNode.tilted() || c.RightNode.tilted()
+}
+
 func (w *window) subWindow(r image.Rectangle) *window {
 	res := &window{
 		mi:   w.mi,
 		miSq: w.miSq,
+		mt:   w.mt,
 		rect: r,
 	}
 	res.init()
@@ -103,31 +172,102 @@ func (w *window) subWindow(r image.Rectangle) *window {
 }
 
 func (c *Classifier) classify(w *window, pr *projector) float64 {
-	s := 0.0
-	for _, f := range c.Feature {
-		s += float64(w.mi.sum(pr.rect(f.Rect))) * f.Weight
+	var left bool
+	if c.Subset != nil {
+		v := w.lbp(pr, c.Feature[0].Rect)
+		left = c.Subset[v>>5]&(1<<(v&31)) != 0
+	} else {
+		s := 0.0
+		for _, f := range c.Feature {
+			if f.Tilted {
+				x, y, fw, fh := pr.tilted(f.Rect)
+				s += float64(w.mt.tiltedSum(x, y, fw, fh)) * f.Weight
+			} else {
+				s += float64(w.mi.sum(pr.rect(f.Rect))) * f.Weight
+			}
+		}
+		s *= w.invArea // normalize to maintain scale invariance
+		left = s < c.Threshold*w.stdDev
 	}
-	s *= w.invArea // normalize to maintain scale invariance
-	if s < c.Threshold*w.stdDev {
+	if left {
+		if c.LeftNode != nil {
+			return c.LeftNode.classify(w, pr)
+		}
 		return c.Left
 	}
+	if c.RightNode != nil {
+		return c.RightNode.classify(w, pr)
+	}
 	return c.Right
 }
 
+// lbpBits is the bit of the local binary pattern for each block of the
+// grid, in row major order, as in OpenCV. The middle block has none.
+var lbpBits = [9]uint{128, 64, 32, 1, 0, 16, 2, 4, 8}
+
+// lbp returns the local binary pattern of the 3x3 grid of blocks whose
+// first block is r. A block's bit is set if its sum is at least that of the
+// middle block.
+func (w *window) lbp(pr *projector, r image.Rectangle) uint {
+	p := pr.pt(r.Min)
+	bw := int(float64(r.Dx())*pr.rx + 0.5)
+	bh := int(float64(r.Dy())*pr.ry + 0.5)
+
+	// Keep the grid within the window, despite rounding.
+	if n := (pr.r.Max.X - p.X) / 3; bw > n {
+		bw = n
+	}
+	if n := (pr.r.Max.Y - p.Y) / 3; bh > n {
+		bh = n
+	}
+	if bw < 1 || bh < 1 {
+		return 0
+	}
+
+	var sums [9]uint64
+	for i := range sums {
+		x, y := p.X+(i%3)*bw, p.Y+(i/3)*bh
+		sums[i] = w.mi.sum(image.Rect(x, y, x+bw, y+bh))
+	}
+	v := uint(0)
+	for i, s := range sums {
+		if i != 4 && s >= sums[4] {
+			v |= lbpBits[i]
+		}
+	}
+	return v
+}
+
 func (s *CascadeStage) classify(w *window, pr *projector) bool {
 	sum := 0.0
 	for _, c := range s.Classifier {
 		sum += c.classify(w, pr)
 	}
-	return sum >= s.Threshold
+	if sum < s.Threshold {
+		return false
+	}
+	if s.Branches == nil {
+		return true
+	}
+	for _, b := range s.Branches {
+		if classifyStages(b, w, pr) {
+			return true
+		}
+	}
+	return false
 }
 
-func (c *Cascade) classify(w *window) bool {
-	pr := newProjector(w.rect, image.Rectangle{image.Pt(0, 0), c.Size})
-	for _, s := range c.Stage {
-		if !s.classify(w, pr) {
+// classifyStages reports whether w passes each of stages.
+func classifyStages(stages []CascadeStage, w *window, pr *projector) bool {
+	for i := range stages {
+		if !stages[i].classify(w, pr) {
 			return false
 		}
 	}
 	return true
 }
+
+func (c *Cascade) classify(w *window) bool {
+	pr := newProjector(w.rect, image.Rectangle{image.Pt(0, 0), c.Size})
+	return classifyStages(c.Stage, w, pr)
+}
@@ -6,6 +6,7 @@ package detect
 
 import (
 	"image"
+	"image/color"
 	"image/draw"
 	"testing"
 )
@@ -75,3 +76,128 @@ func TestClassifierScale(t *testing.T) {
 		t.Errorf("scaled c2 got %f want %f", res, c1.Left)
 	}
 }
+
+func TestClassifierTilted(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 20, 20))
+	b := m.Bounds()
+	draw.Draw(m, image.Rect(9, 7, 12, 10), image.White, image.ZP, draw.Src)
+
+	// The diamond with its top corner at (10, 4) holds the white square,
+	// and the one at (4, 1) does not.
+	in := Classifier{
+		Feature:   []Feature{{Rect: image.Rect(10, 4, 14, 8), Weight: 1, Tilted: true}},
+		Threshold: 0.1,
+		Left:      0,
+		Right:     1,
+	}
+	out := in
+	out.Feature = []Feature{{Rect: image.Rect(4, 1, 6, 3), Weight: 1, Tilted: true}}
+
+	c := &Cascade{
+		Stage: []CascadeStage{{Classifier: []Classifier{in}, Threshold: 0.5}},
+		Size:  image.Pt(20, 20),
+	}
+	w := c.newWindow(m)
+	if w.mt == nil {
+		t.Fatal("no tilted integral")
+	}
+	pr := newProjector(b, b)
+	if res := in.classify(w, pr); res != in.Right {
+		t.Errorf("in got %f want %f", res, in.Right)
+	}
+	if res := out.classify(w, pr); res != out.Left {
+		t.Errorf("out got %f want %f", res, out.Left)
+	}
+	if !c.Match(m) {
+		t.Error("cascade does not match")
+	}
+}
+
+func TestClassifierTree(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 20, 20))
+	b := m.Bounds()
+	draw.Draw(m, image.Rect(0, 0, 20, 20), image.White, image.ZP, draw.Src)
+	draw.Draw(m, image.Rect(3, 4, 4, 5), image.Black, image.ZP, draw.Src)
+	w := newWindow(m)
+	pr := newProjector(b, b)
+
+	// c1 takes its left branch, to a copy of c0, which takes its right.
+	tree := c1
+	n0, n1 := c0, c1
+	n0.Right = 0.5
+	tree.LeftNode = &n0
+	tree.RightNode = &n1
+	if res := tree.classify(w, pr); res != 0.5 {
+		t.Errorf("got %f want 0.5", res)
+	}
+}
+
+// lbpImage returns a 6x6 image of 2x2 blocks, with the given values in row
+// major order.
+func lbpImage(blocks [9]uint8) *image.Gray {
+	m := image.NewGray(image.Rect(0, 0, 6, 6))
+	for i, v := range blocks {
+		x, y := i%3*2, i/3*2
+		draw.Draw(m, image.Rect(x, y, x+2, y+2), image.NewUniform(color.Gray{v}), image.ZP, draw.Src)
+	}
+	return m
+}
+
+func TestLBP(t *testing.T) {
+	tests := []struct {
+		blocks [9]uint8
+		want   uint
+	}{
+		{[9]uint8{0, 0, 0, 0, 9, 0, 0, 0, 0}, 0},
+		{[9]uint8{5, 5, 5, 5, 5, 5, 5, 5, 5}, 255},
+		{[9]uint8{9, 0, 0, 0, 5, 0, 0, 0, 0}, 128},
+		{[9]uint8{0, 0, 0, 9, 5, 0, 0, 0, 9}, 1 | 8},
+		{[9]uint8{0, 9, 9, 0, 5, 9, 0, 9, 0}, 64 | 32 | 16 | 4},
+	}
+	for i, tc := range tests {
+		m := lbpImage(tc.blocks)
+		b := m.Bounds()
+		w := newWindow(m)
+		if got := w.lbp(newProjector(b, b), image.Rect(0, 0, 2, 2)); got != tc.want {
+			t.Errorf("%d: got %d want %d", i, got, tc.want)
+		}
+
+		// At twice the size, the blocks are twice as large.
+		big := image.NewGray(image.Rect(0, 0, 12, 12))
+		for y := 0; y < 12; y++ {
+			for x := 0; x < 12; x++ {
+				big.SetGray(x, y, m.GrayAt(x/2, y/2))
+			}
+		}
+		w = newWindow(big)
+		if got := w.lbp(newProjector(big.Bounds(), b), image.Rect(0, 0, 2, 2)); got != tc.want {
+			t.Errorf("%d scaled: got %d want %d", i, got, tc.want)
+		}
+	}
+}
+
+func TestCascadeBranches(t *testing.T) {
+	// Stages without classifiers pass if their threshold is not positive.
+	pass := CascadeStage{Threshold: 0}
+	fail := CascadeStage{Threshold: 1}
+	branch := func(branches ...[]CascadeStage) CascadeStage {
+		return CascadeStage{Branches: branches}
+	}
+	tests := []struct {
+		stages []CascadeStage
+		want   bool
+	}{
+		{[]CascadeStage{branch([]CascadeStage{fail}, []CascadeStage{pass})}, true},
+		{[]CascadeStage{branch([]CascadeStage{fail}, []CascadeStage{pass, fail})}, false},
+		{[]CascadeStage{branch([]CascadeStage{pass, fail}, []CascadeStage{pass, pass})}, true},
+		{[]CascadeStage{branch([]CascadeStage{pass}), fail}, false},
+		{[]CascadeStage{fail, branch([]CascadeStage{pass})}, false},
+	}
+	m := image.NewGray(image.Rect(0, 0, 10, 10))
+	for i, tc := range tests {
+		c := &Cascade{Stage: tc.stages, Size: image.Pt(10, 10)}
+		if got := c.Match(m); got != tc.want {
+			t.Errorf("%d: got %t want %t", i, got, tc.want)
+		}
+	}
+}
@@ -27,5 +27,12 @@ object using Detect
 It is also possible to search an image for occurrences of an object
 
 	objs := classifier.Find(m)
+
+Each object is usually found several times, at nearby positions and scales.
+Detect merges these matches, and drops those found too few times
+
+	for _, d := range classifier.Detect(m, nil) {
+		// d.Rect was matched d.Neighbors times.
+	}
 */
 package detect
@@ -0,0 +1,188 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package detect
+
+import (
+	"image"
+	"sort"
+)
+
+// Detection is a group of overlapping matches, merged into one rectangle.
+// Neighbors is the number of matches in the group.
+type Detection struct {
+	Rect      image.Rectangle
+	Neighbors int
+}
+
+// GroupOptions are optional parameters to Group.
+//
+// MinNeighbors is the least number of matches a group needs to be kept.
+// Eps is how far apart two matches may be, relative to their size, to be
+// in the same group. Overlap is the greatest intersection over union of two
+// groups; of two groups that overlap more, only the one with more matches
+// is kept. An Overlap of 1 keeps them both.
+type GroupOptions struct {
+	MinNeighbors int
+	Eps          float64
+	Overlap      float64
+}
+
+// DefaultGroupOptions are the options Group uses when none are given. They
+// are those of OpenCV's groupRectangles, with non-maximum suppression added.
+var DefaultGroupOptions = GroupOptions{
+	MinNeighbors: 3,
+	Eps:          0.2,
+	Overlap:      0.3,
+}
+
+// Detect returns the objects in m that match c. The matches found by Find
+// are merged with Group.
+func (c *Cascade) Detect(m image.Image, opt *GroupOptions) []Detection {
+	return Group(c.Find(m), opt)
+}
+
+// Group merges overlapping rectangles, such as those returned by Find, as
+// OpenCV's groupRectangles does. Rectangles are grouped if they are close
+// in position and size, and each group is merged into their mean. Groups
+// with too few rectangles are dropped, as are those inside a group with
+// more. Then, of groups that overlap by more than opt.Overlap, only the
+// one with the most rectangles is kept. The detections are returned in
+// decreasing order of Neighbors. A nil opt means DefaultGroupOptions.
+func Group(rects []image.Rectangle, opt *GroupOptions) []Detection {
+	if opt == nil {
+		opt = &DefaultGroupOptions
+	}
+
+	// Partition the rectangles into groups of similar ones.
+	parent := make([]int, len(rects))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range rects {
+		for j := i + 1; j < len(rects); j++ {
+			if similar(rects[i], rects[j], opt.Eps) {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	// Merge each group into its mean.
+	var sums []image.Rectangle
+	var counts []int
+	index := map[int]int{}
+	for i, r := range rects {
+		root := find(i)
+		g, ok := index[root]
+		if !ok {
+			g = len(sums)
+			index[root] = g
+			sums = append(sums, image.Rectangle{})
+			counts = append(counts, 0)
+		}
+		sums[g].Min = sums[g].Min.Add(r.Min)
+		sums[g].Max = sums[g].Max.Add(r.Max)
+		counts[g]++
+	}
+	var groups []Detection
+	for g, s := range sums {
+		n := counts[g]
+		if n < opt.MinNeighbors {
+			continue
+		}
+		mean := func(v int) int { return (2*v + n) / (2 * n) }
+		groups = append(groups, Detection{
+			Rect:      image.Rect(mean(s.Min.X), mean(s.Min.Y), mean(s.Max.X), mean(s.Max.Y)),
+			Neighbors: n,
+		})
+	}
+
+	// Drop groups inside groups with more neighbors. As in OpenCV, a group
+	// with fewer than 3 neighbors is dropped if it is inside any other.
+	var kept []Detection
+	for i, d := range groups {
+		inside := false
+		for j, o := range groups {
+			if i == j || o.Neighbors <= maxInt(3, d.Neighbors) && d.Neighbors >= 3 {
+				continue
+			}
+			dx := int(float64(o.Rect.Dx())*opt.Eps + 0.5)
+			dy := int(float64(o.Rect.Dy())*opt.Eps + 0.5)
+			grown := image.Rect(o.Rect.Min.X-dx, o.Rect.Min.Y-dy, o.Rect.Max.X+dx, o.Rect.Max.Y+dy)
+			if d.Rect.In(grown) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			kept = append(kept, d)
+		}
+	}
+
+	// Non-maximum suppression.
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].Neighbors > kept[j].Neighbors })
+	res := []Detection{}
+	for _, d := range kept {
+		suppressed := false
+		for _, r := range res {
+			if overlap(d.Rect, r.Rect) > opt.Overlap {
+				suppressed = true
+				break
+			}
+		}
+		if !suppressed {
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+// similar reports whether r0 and r1 differ in each edge by no more than eps
+// times their mean smaller side.
+func similar(r0, r1 image.Rectangle, eps float64) bool {
+	delta := eps * float64(minInt(r0.Dx(), r1.Dx())+minInt(r0.Dy(), r1.Dy())) / 2
+	return absInt(r0.Min.X-r1.Min.X) <= int(delta) &&
+		absInt(r0.Min.Y-r1.Min.Y) <= int(delta) &&
+		absInt(r0.Max.X-r1.Max.X) <= int(delta) &&
+		absInt(r0.Max.Y-r1.Max.Y) <= int(delta)
+}
+
+// overlap returns the area of the intersection of r0 and r1 divided by the
+// area of their union.
+func overlap(r0, r1 image.Rectangle) float64 {
+	i := r0.Intersect(r1)
+	if i.Empty() {
+		return 0
+	}
+	ai := i.Dx() * i.Dy()
+	return float64(ai) / float64(r0.Dx()*r0.Dy()+r1.Dx()*r1.Dy()-ai)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
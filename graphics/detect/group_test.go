@@ -0,0 +1,62 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package detect
+
+import (
+	"image"
+	"testing"
+)
+
+// repeat returns n copies of r.
+func repeat(r image.Rectangle, n int) []image.Rectangle {
+	rs := make([]image.Rectangle, n)
+	for i := range rs {
+		rs[i] = r
+	}
+	return rs
+}
+
+func TestGroup(t *testing.T) {
+	var rects []image.Rectangle
+	// Four matches around one object.
+	rects = append(rects,
+		image.Rect(10, 10, 30, 30),
+		image.Rect(11, 10, 31, 30),
+		image.Rect(9, 10, 29, 30),
+		image.Rect(10, 11, 30, 31),
+	)
+	// Too few matches.
+	rects = append(rects, repeat(image.Rect(100, 100, 120, 120), 2)...)
+	// Inside the first object, with fewer matches.
+	rects = append(rects, repeat(image.Rect(14, 14, 20, 20), 3)...)
+	// Two objects that are not similar, but overlap.
+	rects = append(rects, repeat(image.Rect(200, 0, 240, 40), 5)...)
+	rects = append(rects, repeat(image.Rect(215, 0, 255, 40), 3)...)
+
+	got := Group(rects, nil)
+	want := []Detection{
+		{image.Rect(200, 0, 240, 40), 5},
+		{image.Rect(10, 10, 30, 30), 4},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%d: got %v want %v", i, got[i], want[i])
+		}
+	}
+
+	// Without suppression, both overlapping objects are kept.
+	opt := DefaultGroupOptions
+	opt.Overlap = 1
+	if got := Group(rects, &opt); len(got) != 3 || got[2] != (Detection{image.Rect(215, 0, 255, 40), 3}) {
+		t.Errorf("no suppression: got %v", got)
+	}
+
+	if got := Group(nil, nil); len(got) != 0 {
+		t.Errorf("no rectangles: got %v", got)
+	}
+}
@@ -59,14 +59,21 @@ func (m *integral) integrate() {
 	}
 }
 
+// toGray returns src as an *image.Gray, converting it if need be.
+func toGray(src image.Image) *image.Gray {
+	if g, ok := src.(*image.Gray); ok {
+		return g
+	}
+	b := src.Bounds()
+	g := image.NewGray(b)
+	draw.Draw(g, b, src, b.Min, draw.Src)
+	return g
+}
+
 // newIntegrals returns the integral and the squared integral.
 func newIntegrals(src image.Image) (*integral, *integral) {
 	b := src.Bounds()
-	srcg, ok := src.(*image.Gray)
-	if !ok {
-		srcg = image.NewGray(b)
-		draw.Draw(srcg, b, src, b.Min, draw.Src)
-	}
+	srcg := toGray(src)
 
 	m := integral{
 		pix:    make([]uint64, b.Max.Y*b.Max.X),
@@ -91,3 +98,57 @@ func newIntegrals(src image.Image) (*integral, *integral) {
 	mSq.integrate()
 	return &m, &mSq
 }
+
+// tiltedSum returns the sum of the pixels in the rectangle w wide and h
+// high, rotated by 45 degrees clockwise about its top corner (x, y), as in
+// OpenCV. p must be a tilted integral.
+func (p *integral) tiltedSum(x, y, w, h int) uint64 {
+	return p.at(x, y) - p.at(x-h, y+h) - p.at(x+w, y+w) + p.at(x+w-h, y+w+h)
+}
+
+// newTiltedIntegral returns the rotated integral of src. Its value at
+// (x, y), which may be one past the right or bottom edge of src, is the sum
+// of the pixels (x', y') with y' < y and |x'-x+1| <= y-y'-1, which is the
+// triangle whose apex is the pixel above and to the left of (x, y). See R.
+// Lienhart, J. Maydt. An Extended Set of Haar-like Features for Rapid
+// Object Detection, ICIP 2002.
+func newTiltedIntegral(src image.Image) *integral {
+	b := src.Bounds()
+	g := toGray(src)
+	w, h := b.Dx(), b.Dy()
+	m := &integral{
+		pix:    make([]uint64, (w+1)*(h+1)),
+		stride: w + 1,
+		rect:   image.Rect(b.Min.X, b.Min.Y, b.Max.X+1, b.Max.Y+1),
+	}
+	pixel := func(x, y int) uint64 {
+		if x < 0 || x >= w || y < 0 || y >= h {
+			return 0
+		}
+		return uint64(g.Pix[y*g.Stride+x])
+	}
+
+	// The triangles reach beyond the sides of src, so each row is computed
+	// pad further out on either side. The error from ignoring what lies
+	// beyond that moves in by one column a row, and never reaches src.
+	pad := h + 1
+	ew := w + 1 + 2*pad
+	prev2, prev1 := make([]uint64, ew), make([]uint64, ew)
+	for y := 1; y <= h; y++ {
+		cur := make([]uint64, ew)
+		for i := range cur {
+			x := i - pad
+			v := pixel(x-1, y-1) + pixel(x-1, y-2) - prev2[i]
+			if i > 0 {
+				v += prev1[i-1]
+			}
+			if i < ew-1 {
+				v += prev1[i+1]
+			}
+			cur[i] = v
+		}
+		copy(m.pix[y*m.stride:(y+1)*m.stride], cur[pad:pad+w+1])
+		prev2, prev1 = prev1, cur
+	}
+	return m
+}
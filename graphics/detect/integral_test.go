@@ -154,3 +154,51 @@ func TestIntegralSubImage(t *testing.T) {
 		t.Errorf("r0 got %d want %d", sum1, sum0)
 	}
 }
+
+func TestTiltedIntegral(t *testing.T) {
+	m0 := &image.Gray{
+		Pix: []uint8{
+			0x02, 0x03, 0x00, 0x01, 0x03,
+			0x01, 0x02, 0x01, 0x05, 0x05,
+			0x01, 0x04, 0x01, 0x01, 0x02,
+			0x01, 0x02, 0x01, 0x01, 0x07,
+			0x02, 0x01, 0x09, 0x03, 0x01,
+		},
+		Stride: 5,
+		Rect:   image.Rect(0, 0, 5, 5),
+	}
+	for _, m := range []*image.Gray{m0, m0.SubImage(image.Rect(1, 0, 5, 4)).(*image.Gray)} {
+		b := m.Bounds()
+		mt := newTiltedIntegral(m)
+
+		// Compare with the definition.
+		for y := b.Min.Y; y <= b.Max.Y; y++ {
+			for x := b.Min.X; x <= b.Max.X; x++ {
+				want := uint64(0)
+				for py := b.Min.Y; py < y; py++ {
+					for px := b.Min.X; px < b.Max.X; px++ {
+						d := px - x + 1
+						if d < 0 {
+							d = -d
+						}
+						if d <= y-py-1 {
+							want += uint64(m.GrayAt(px, py).Y)
+						}
+					}
+				}
+				if got := mt.at(x, y); got != want {
+					t.Errorf("%v (%d, %d): got %d want %d", b, x, y, got, want)
+				}
+			}
+		}
+	}
+
+	// A tilted rectangle w by h covers 2*w*h pixels.
+	mt := newTiltedIntegral(m0)
+	if got, want := mt.tiltedSum(2, 0, 1, 1), uint64(0x03+0x02); got != want {
+		t.Errorf("1x1: got %d want %d", got, want)
+	}
+	if got, want := mt.tiltedSum(2, 1, 2, 1), uint64(0x02+0x04+0x01+0x01); got != want {
+		t.Errorf("2x1: got %d want %d", got, want)
+	}
+}
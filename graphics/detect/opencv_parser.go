@@ -16,19 +16,49 @@ import (
 	"strings"
 )
 
-type xmlFeature struct {
-	Rects     []string `xml:"grp>feature>rects>grp"`
-	Tilted    int      `xml:"grp>feature>tilted"`
-	Threshold float64  `xml:"grp>threshold"`
-	Left      float64  `xml:"grp>left_val"`
-	Right     float64  `xml:"grp>right_val"`
+// xmlNode is a node of a tree. A child is either a value or the index of
+// another node in the tree.
+type xmlNode struct {
+	Rects     []string `xml:"feature>rects>grp"`
+	Tilted    int      `xml:"feature>tilted"`
+	Threshold float64  `xml:"threshold"`
+	Left      float64  `xml:"left_val"`
+	Right     float64  `xml:"right_val"`
+	LeftNode  *int     `xml:"left_node"`
+	RightNode *int     `xml:"right_node"`
+}
+
+type xmlTree struct {
+	Nodes []xmlNode `xml:"grp"`
 }
 
 type xmlStages struct {
-	Trees           []xmlFeature `xml:"trees>grp"`
-	Stage_threshold float64      `xml:"stage_threshold"`
-	Parent          int          `xml:"parent"`
-	Next            int          `xml:"next"`
+	Trees           []xmlTree `xml:"trees>grp"`
+	Stage_threshold float64   `xml:"stage_threshold"`
+	Parent          int       `xml:"parent"`
+	Next            int       `xml:"next"`
+
+	// The new format.
+	StageThreshold  float64   `xml:"stageThreshold"`
+	WeakClassifiers []xmlWeak `xml:"weakClassifiers>grp"`
+}
+
+// xmlWeak is a tree in the new format. InternalNodes holds, for each node,
+// the left and right children, the index of the feature and then the
+// threshold for Haar-like features, or the lbpSubsetSize words of the
+// subset for local binary patterns. A child that is not positive is the
+// negated index of a value in LeafValues.
+type xmlWeak struct {
+	InternalNodes string `xml:"internalNodes"`
+	LeafValues    string `xml:"leafValues"`
+}
+
+// xmlFeature is a Haar-like feature, with Rects and Tilted, or a local
+// binary pattern feature, with Rect.
+type xmlFeature struct {
+	Rects  []string `xml:"rects>grp"`
+	Tilted int      `xml:"tilted"`
+	Rect   string   `xml:"rect"`
 }
 
 type opencv_storage struct {
@@ -37,13 +67,20 @@ type opencv_storage struct {
 		Type    string      `xml:"type_id,attr"`
 		Size    string      `xml:"size"`
 		Stages  []xmlStages `xml:"stages>grp"`
+
+		// The new format.
+		StageType   string       `xml:"stageType"`
+		FeatureType string       `xml:"featureType"`
+		Width       int          `xml:"width"`
+		Height      int          `xml:"height"`
+		Features    []xmlFeature `xml:"features>grp"`
 	} `xml:",any"`
 }
 
 func buildFeature(r string) (f Feature, err error) {
 	var x, y, w, h int
 	var weight float64
-	_, err = fmt.Sscanf(r, "%d %d %d %d %f", &x, &y, &w, &h, &weight)
+	_, err = fmt.Sscanf(strings.TrimSpace(r), "%d %d %d %d %f", &x, &y, &w, &h, &weight)
 	if err != nil {
 		return
 	}
@@ -53,8 +90,12 @@ func buildFeature(r string) (f Feature, err error) {
 }
 
 func buildCascade(s *opencv_storage) (c *Cascade, name string, err error) {
-	if s.Any.Type != "opencv-haar-classifier" {
-		err = fmt.Errorf("got %s want opencv-haar-classifier", s.Any.Type)
+	switch s.Any.Type {
+	case "opencv-haar-classifier":
+	case "opencv-cascade-classifier":
+		return buildBoostCascade(s)
+	default:
+		err = fmt.Errorf("got %s want opencv-haar-classifier or opencv-cascade-classifier", s.Any.Type)
 		return
 	}
 	name = s.Any.XMLName.Local
@@ -70,43 +111,270 @@ func buildCascade(s *opencv_storage) (c *Cascade, name string, err error) {
 		return nil, "", err
 	}
 	c.Size = image.Pt(w, h)
-	c.Stage = []CascadeStage{}
+	// Each stage names its parent and its next sibling. The first child
+	// of a stage is the first stage that names it as parent. A chain of
+	// stages is a tree in which each stage is the only child of the one
+	// before.
+	n := len(s.Any.Stages)
+	stages := make([]CascadeStage, n)
+	child, next := make([]int, n), make([]int, n)
+	for i := range child {
+		child[i] = -1
+	}
+	for i, stage := range s.Any.Stages {
+		if stage.Parent < -1 || stage.Parent >= i {
+			return nil, "", fmt.Errorf("graphics: stage %d: bad parent %d", i, stage.Parent)
+		}
+		if stage.Next != -1 && (stage.Next <= i || stage.Next >= n) {
+			return nil, "", fmt.Errorf("graphics: stage %d: bad next %d", i, stage.Next)
+		}
+		if p := stage.Parent; p >= 0 && child[p] == -1 {
+			child[p] = i
+		}
+		next[i] = stage.Next
 
-	for _, stage := range s.Any.Stages {
 		cs := CascadeStage{
 			Classifier: []Classifier{},
 			Threshold:  stage.Stage_threshold,
 		}
 		for _, tree := range stage.Trees {
-			if tree.Tilted != 0 {
-				err = errors.New("Cascade does not support tilted features")
-				return
+			if len(tree.Nodes) == 0 {
+				return nil, "", errors.New("graphics: tree has no nodes")
+			}
+			cls, err := buildClassifier(tree.Nodes, 0)
+			if err != nil {
+				return nil, "", err
 			}
+			cs.Classifier = append(cs.Classifier, *cls)
+		}
+		stages[i] = cs
+	}
 
-			cls := Classifier{
-				Feature:   []Feature{},
-				Threshold: tree.Threshold,
-				Left:      tree.Left,
-				Right:     tree.Right,
+	switch {
+	case n == 0:
+		c.Stage = []CascadeStage{}
+	case next[0] == -1:
+		c.Stage = buildStages(stages, child, next, 0)
+	default:
+		// Several roots are the branches of a stage that always passes.
+		root := CascadeStage{Classifier: []Classifier{}}
+		for b := 0; b != -1; b = next[b] {
+			root.Branches = append(root.Branches, buildStages(stages, child, next, b))
+		}
+		c.Stage = []CascadeStage{root}
+	}
+
+	return
+}
+
+// buildClassifier returns the classifier for node i of a tree and its
+// children. Children must come after their parents, so there are no cycles.
+func buildClassifier(nodes []xmlNode, i int) (*Classifier, error) {
+	n := nodes[i]
+	cls := &Classifier{
+		Feature:   []Feature{},
+		Threshold: n.Threshold,
+		Left:      n.Left,
+		Right:     n.Right,
+	}
+	for _, rect := range n.Rects {
+		f, err := buildFeature(rect)
+		if err != nil {
+			return nil, err
+		}
+		f.Tilted = n.Tilted != 0
+		cls.Feature = append(cls.Feature, f)
+	}
+
+	child := func(j *int) (*Classifier, error) {
+		if j == nil {
+			return nil, nil
+		}
+		if *j <= i || *j >= len(nodes) {
+			return nil, fmt.Errorf("graphics: node %d has bad child %d", i, *j)
+		}
+		return buildClassifier(nodes, *j)
+	}
+	var err error
+	if cls.LeftNode, err = child(n.LeftNode); err != nil {
+		return nil, err
+	}
+	if cls.RightNode, err = child(n.RightNode); err != nil {
+		return nil, err
+	}
+	return cls, nil
+}
+
+// buildStages returns the stages from stages[i] down through each only
+// child. The children of a stage with more than one are its branches.
+func buildStages(stages []CascadeStage, child, next []int, i int) []CascadeStage {
+	var chain []CascadeStage
+	for {
+		s := stages[i]
+		c := child[i]
+		if c == -1 {
+			return append(chain, s)
+		}
+		if next[c] != -1 {
+			for b := c; b != -1; b = next[b] {
+				s.Branches = append(s.Branches, buildStages(stages, child, next, b))
 			}
+			return append(chain, s)
+		}
+		chain = append(chain, s)
+		i = c
+	}
+}
+
+// thresholdEps is subtracted from stage thresholds in the new format, as
+// OpenCV does, so that rounding does not reject borderline windows.
+const thresholdEps = 1e-5
+
+// buildBoostCascade builds a cascade in the new format, written by OpenCV
+// 2.4 and later.
+func buildBoostCascade(s *opencv_storage) (*Cascade, string, error) {
+	a := &s.Any
+	if a.StageType != "BOOST" {
+		return nil, "", fmt.Errorf("graphics: unsupported stage type %q", a.StageType)
+	}
+	stride := 4
+	switch a.FeatureType {
+	case "HAAR":
+	case "LBP":
+		stride = 3 + lbpSubsetSize
+	default:
+		return nil, "", fmt.Errorf("graphics: unsupported feature type %q", a.FeatureType)
+	}
 
-			for _, rect := range tree.Rects {
-				f, err := buildFeature(rect)
-				if err != nil {
-					return nil, "", err
-				}
-				cls.Feature = append(cls.Feature, f)
+	features := make([][]Feature, len(a.Features))
+	for i, xf := range a.Features {
+		if stride != 4 {
+			var x, y, w, h int
+			if _, err := fmt.Sscanf(strings.TrimSpace(xf.Rect), "%d %d %d %d", &x, &y, &w, &h); err != nil {
+				return nil, "", err
 			}
+			features[i] = []Feature{{Rect: image.Rect(x, y, x+w, y+h)}}
+			continue
+		}
+		features[i] = []Feature{}
+		for _, rect := range xf.Rects {
+			f, err := buildFeature(rect)
+			if err != nil {
+				return nil, "", err
+			}
+			f.Tilted = xf.Tilted != 0
+			features[i] = append(features[i], f)
+		}
+	}
 
-			cs.Classifier = append(cs.Classifier, cls)
+	c := &Cascade{
+		Stage: []CascadeStage{},
+		Size:  image.Pt(a.Width, a.Height),
+	}
+	for _, stage := range a.Stages {
+		cs := CascadeStage{
+			Classifier: []Classifier{},
+			Threshold:  stage.StageThreshold - thresholdEps,
+		}
+		for _, weak := range stage.WeakClassifiers {
+			nodes := strings.Fields(weak.InternalNodes)
+			leaves, err := parseFloats(weak.LeafValues)
+			if err != nil {
+				return nil, "", err
+			}
+			if len(nodes) == 0 || len(nodes)%stride != 0 {
+				return nil, "", fmt.Errorf("graphics: bad internal nodes %q", weak.InternalNodes)
+			}
+			cls, err := buildBoostNode(nodes, stride, leaves, features, 0)
+			if err != nil {
+				return nil, "", err
+			}
+			cs.Classifier = append(cs.Classifier, *cls)
 		}
 		c.Stage = append(c.Stage, cs)
 	}
+	return c, a.XMLName.Local, nil
+}
 
-	return
+// lbpSubsetSize is the number of 32 bit words in the subset of a local
+// binary pattern node, one bit for each of the 256 patterns.
+const lbpSubsetSize = 8
+
+// buildBoostNode returns the classifier for node i, and its children, of a
+// tree in the new format. Each node is stride numbers long.
+func buildBoostNode(nodes []string, stride int, leaves []float64, features [][]Feature, i int) (*Classifier, error) {
+	n := nodes[stride*i : stride*i+stride]
+	left, err0 := strconv.Atoi(n[0])
+	right, err1 := strconv.Atoi(n[1])
+	fi, err2 := strconv.Atoi(n[2])
+	for _, err := range []error{err0, err1, err2} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	if fi < 0 || fi >= len(features) {
+		return nil, fmt.Errorf("graphics: node %d has bad feature %d", i, fi)
+	}
+	cls := &Classifier{Feature: features[fi]}
+	if stride == 4 {
+		t, err := strconv.ParseFloat(n[3], 64)
+		if err != nil {
+			return nil, err
+		}
+		cls.Threshold = t
+	} else {
+		// The subset is written as signed integers.
+		cls.Subset = make([]uint32, lbpSubsetSize)
+		for j := range cls.Subset {
+			v, err := strconv.ParseInt(n[3+j], 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			cls.Subset[j] = uint32(v)
+		}
+	}
+
+	// Children must come after their parents, so there are no cycles.
+	child := func(j int, val *float64, node **Classifier) (err error) {
+		if j <= 0 {
+			if -j >= len(leaves) {
+				return fmt.Errorf("graphics: node %d has bad leaf %d", i, -j)
+			}
+			*val = leaves[-j]
+			return nil
+		}
+		if j <= i || stride*j >= len(nodes) {
+			return fmt.Errorf("graphics: node %d has bad child %d", i, j)
+		}
+		*node, err = buildBoostNode(nodes, stride, leaves, features, j)
+		return err
+	}
+	if err := child(left, &cls.Left, &cls.LeftNode); err != nil {
+		return nil, err
+	}
+	if err := child(right, &cls.Right, &cls.RightNode); err != nil {
+		return nil, err
+	}
+	return cls, nil
+}
+
+// parseFloats parses a list of numbers separated by spaces.
+func parseFloats(s string) ([]float64, error) {
+	fields := strings.Fields(s)
+	v := make([]float64, len(fields))
+	for i, f := range fields {
+		var err error
+		if v[i], err = strconv.ParseFloat(f, 64); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
 }
 
-// ParseOpenCV produces a detection Cascade from an OpenCV XML file.
+// ParseOpenCV produces a detection Cascade from an OpenCV XML file, in
+// either the old format, with type_id opencv-haar-classifier, or the new
+// format, with type_id opencv-cascade-classifier. In the old format, the
+// stages may make a tree, as in haarcascade_frontalface_alt_tree.xml.
 func ParseOpenCV(r io.Reader) (cascade *Cascade, name string, err error) {
 	// BUG(crawshaw): tag-based parsing doesn't seem to work with <_>
 	buf, err := ioutil.ReadAll(r)
@@ -5,9 +5,13 @@
 package detect
 
 import (
+	"bytes"
+	"fmt"
 	"image"
+	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -73,3 +77,256 @@ func TestParseOpenCV(t *testing.T) {
 		t.Errorf("got\n %v want\n %v", *cascadeFile, cascade)
 	}
 }
+
+func TestParseOpenCVTilted(t *testing.T) {
+	buf, err := ioutil.ReadFile("../../testdata/opencv.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf = bytes.Replace(buf, []byte("<tilted>0</tilted>"), []byte("<tilted>1</tilted>"), 1)
+	c, _, err := ParseOpenCV(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, cl := range c.Stage[0].Classifier {
+		for _, f := range cl.Feature {
+			if f.Tilted != (i == 0) {
+				t.Errorf("classifier %d: tilted %t", i, f.Tilted)
+			}
+		}
+	}
+}
+
+const treeXML = `<?xml version="1.0"?>
+<opencv_storage>
+<tree_cascade type_id="opencv-haar-classifier">
+  <size>20 20</size>
+  <stages>
+    <_>
+      <trees>
+        <_>
+          <_>
+            <feature>
+              <rects>
+                <_>0 0 3 4 -1.</_></rects>
+              <tilted>0</tilted></feature>
+            <threshold>0.03</threshold>
+            <left_val>0.01</left_val>
+            <right_node>1</right_node></_>
+          <_>
+            <feature>
+              <rects>
+                <_>3 4 2 2 3.1</_></rects>
+              <tilted>1</tilted></feature>
+            <threshold>0.05</threshold>
+            <left_val>0.2</left_val>
+            <right_val>0.7</right_val></_>
+        </_>
+      </trees>
+      <stage_threshold>0.5</stage_threshold>
+      <parent>-1</parent>
+      <next>-1</next>
+    </_>
+  </stages>
+</tree_cascade>
+</opencv_storage>
+`
+
+func TestParseOpenCVTree(t *testing.T) {
+	c, name, err := ParseOpenCV(strings.NewReader(treeXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "tree_cascade" {
+		t.Errorf("name: got %s want tree_cascade", name)
+	}
+	want := Classifier{
+		Feature:   []Feature{{Rect: image.Rect(0, 0, 3, 4), Weight: -1}},
+		Threshold: 0.03,
+		Left:      0.01,
+		RightNode: &Classifier{
+			Feature:   []Feature{{Rect: image.Rect(3, 4, 5, 6), Weight: 3.1, Tilted: true}},
+			Threshold: 0.05,
+			Left:      0.2,
+			Right:     0.7,
+		},
+	}
+	if len(c.Stage) != 1 || len(c.Stage[0].Classifier) != 1 {
+		t.Fatalf("got %d stages", len(c.Stage))
+	}
+	if got := c.Stage[0].Classifier[0]; !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n %v want\n %v", got, want)
+	}
+	if !c.tilted() {
+		t.Error("tilted feature in a child node not found")
+	}
+
+	bad := []string{
+		strings.Replace(treeXML, "<right_node>1</right_node>", "<right_node>0</right_node>", 1),
+		strings.Replace(treeXML, "<parent>-1</parent>", "<parent>0</parent>", 1),
+	}
+	for i, s := range bad {
+		if _, _, err := ParseOpenCV(strings.NewReader(s)); err == nil {
+			t.Errorf("%d: got nil error", i)
+		}
+	}
+}
+
+// stageTreeXML returns a cascade whose stages, which have no classifiers,
+// have the given parent and next links, and thresholds 1, 2 and so on.
+func stageTreeXML(links [][2]int) string {
+	var b bytes.Buffer
+	b.WriteString("<?xml version=\"1.0\"?>\n<opencv_storage>\n")
+	b.WriteString("<tree_cascade type_id=\"opencv-haar-classifier\">\n<size>20 20</size>\n<stages>\n")
+	for i, l := range links {
+		fmt.Fprintf(&b, "<_><trees></trees><stage_threshold>%d</stage_threshold>", i+1)
+		fmt.Fprintf(&b, "<parent>%d</parent><next>%d</next></_>\n", l[0], l[1])
+	}
+	b.WriteString("</stages>\n</tree_cascade>\n</opencv_storage>\n")
+	return b.String()
+}
+
+func TestParseOpenCVStageTree(t *testing.T) {
+	stage := func(i int, branches ...[]CascadeStage) CascadeStage {
+		return CascadeStage{Classifier: []Classifier{}, Threshold: float64(i + 1), Branches: branches}
+	}
+	tests := []struct {
+		links [][2]int
+		want  []CascadeStage
+	}{
+		// A chain.
+		{
+			[][2]int{{-1, -1}, {0, -1}, {1, -1}},
+			[]CascadeStage{stage(0), stage(1), stage(2)},
+		},
+		// Stage 0 has the children 1 and 2, and stage 2 has the child 3.
+		{
+			[][2]int{{-1, -1}, {0, 2}, {0, -1}, {2, -1}},
+			[]CascadeStage{stage(0, []CascadeStage{stage(1)}, []CascadeStage{stage(2), stage(3)})},
+		},
+		// Two roots.
+		{
+			[][2]int{{-1, 1}, {-1, -1}, {1, -1}},
+			[]CascadeStage{{
+				Classifier: []Classifier{},
+				Branches:   [][]CascadeStage{{stage(0)}, {stage(1), stage(2)}},
+			}},
+		},
+	}
+	for i, tc := range tests {
+		c, _, err := ParseOpenCV(strings.NewReader(stageTreeXML(tc.links)))
+		if err != nil {
+			t.Errorf("%d: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(c.Stage, tc.want) {
+			t.Errorf("%d: got\n %v want\n %v", i, c.Stage, tc.want)
+		}
+	}
+
+	bad := [][][2]int{
+		{{0, -1}},
+		{{-1, -1}, {1, -1}},
+		{{-1, -1}, {0, 1}},
+		{{-1, -1}, {0, 5}},
+	}
+	for i, links := range bad {
+		if _, _, err := ParseOpenCV(strings.NewReader(stageTreeXML(links))); err == nil {
+			t.Errorf("bad %d: got nil error", i)
+		}
+	}
+}
+
+func TestParseOpenCVCascade(t *testing.T) {
+	buf, err := ioutil.ReadFile("../../testdata/opencv_cascade.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, name, err := ParseOpenCV(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "cascade" {
+		t.Errorf("name: got %s want cascade", name)
+	}
+
+	// The same cascade as opencv.xml, in the new format.
+	want := cascade
+	want.Stage = append([]CascadeStage(nil), cascade.Stage...)
+	for i := range want.Stage {
+		want.Stage[i].Threshold -= thresholdEps
+	}
+	if !reflect.DeepEqual(want, *c) {
+		t.Errorf("got\n %v want\n %v", *c, want)
+	}
+
+	// A tree of depth two, whose root goes left to node 1.
+	tree := bytes.Replace(buf, []byte("0 -1 2 0.07"), []byte("1 -2 2 0.07 0 -1 0 0.05"), 1)
+	tree = bytes.Replace(tree, []byte("0.2 0.4<"), []byte("0.2 0.4 0.6<"), 1)
+	c, _, err = ParseOpenCV(bytes.NewReader(tree))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantTree := Classifier{
+		Feature:   classifier2.Feature,
+		Threshold: 0.07,
+		LeftNode: &Classifier{
+			Feature:   classifier0.Feature,
+			Threshold: 0.05,
+			Left:      0.2,
+			Right:     0.4,
+		},
+		Right: 0.6,
+	}
+	if got := c.Stage[1].Classifier[0]; !reflect.DeepEqual(got, wantTree) {
+		t.Errorf("tree: got\n %v want\n %v", got, wantTree)
+	}
+
+	bad := [][]byte{
+		bytes.Replace(buf, []byte("HAAR"), []byte("HOG"), 1),
+		bytes.Replace(buf, []byte("0 -1 2 0.07"), []byte("0 -1 3 0.07"), 1),
+		bytes.Replace(buf, []byte("0 -1 2 0.07"), []byte("0 -2 2 0.07"), 1),
+		bytes.Replace(buf, []byte("0 -1 2 0.07"), []byte("0 1 2 0.07"), 1),
+	}
+	for i, b := range bad {
+		if _, _, err := ParseOpenCV(bytes.NewReader(b)); err == nil {
+			t.Errorf("%d: got nil error", i)
+		}
+	}
+}
+
+func TestParseOpenCVLBP(t *testing.T) {
+	file, err := os.Open("../../testdata/opencv_lbp.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	c, _, err := ParseOpenCV(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Classifier{
+		Feature: []Feature{{Rect: image.Rect(0, 0, 2, 2)}},
+		Left:    1,
+		Right:   -1,
+		Subset:  []uint32{1, 0, 0, 0, 0, 0, 0, 0x80000001},
+	}
+	if got := c.Stage[0].Classifier[0]; !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n %v want\n %v", got, want)
+	}
+
+	// Patterns 0 and 255 are in the subset, and 128 is not.
+	tests := []struct {
+		blocks [9]uint8
+		want   bool
+	}{
+		{[9]uint8{0, 0, 0, 0, 9, 0, 0, 0, 0}, true},
+		{[9]uint8{5, 5, 5, 5, 5, 5, 5, 5, 5}, true},
+		{[9]uint8{9, 0, 0, 0, 5, 0, 0, 0, 0}, false},
+	}
+	for i, tc := range tests {
+		if got := c.Match(lbpImage(tc.blocks)); got != tc.want {
+			t.Errorf("%d: got %t want %t", i, got, tc.want)
+		}
+	}
+}
@@ -42,6 +42,37 @@ func (s *projector) rect(r image.Rectangle) image.Rectangle {
 	return image.Rectangle{s.pt(r.Min), s.pt(r.Max)}
 }
 
+// tilted projects the tilted rectangle r from the source rectangle onto the
+// target rectangle, returning its top corner and its size along each
+// diagonal. It is shrunk, if need be, to fit within the target.
+func (s *projector) tilted(r image.Rectangle) (x, y, w, h int) {
+	p := s.pt(r.Min)
+	scale := (s.rx + s.ry) / 2
+	w = int(float64(r.Dx())*scale + 0.5)
+	h = int(float64(r.Dy())*scale + 0.5)
+	if d := p.X + w - s.r.Max.X; d > 0 {
+		w -= d
+	}
+	if d := s.r.Min.X - (p.X - h); d > 0 {
+		h -= d
+	}
+	if d := p.Y + w + h - s.r.Max.Y; d > 0 {
+		dh := d
+		if dh > h {
+			dh = h
+		}
+		h -= dh
+		w -= d - dh
+	}
+	if w < 0 {
+		w = 0
+	}
+	if h < 0 {
+		h = 0
+	}
+	return p.X, p.Y, w, h
+}
+
 // clamp rounds and clamps o to the integer range [x0, x1].
 func clamp(o float64, x0, x1 int) int {
 	x := int(o + 0.5)
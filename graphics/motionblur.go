@@ -0,0 +1,102 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"errors"
+	"github.com/BurntSushi/graphics-go/graphics/interp"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// MotionBlur produces a blurred version of the image, as if it moved in a
+// straight line during exposure. The motion is length pixels long, in the
+// direction angle, in radians clockwise from the positive x axis. Each
+// pixel is the mean of samples spaced one pixel apart along the line
+// centered on it.
+func MotionBlur(dst draw.Image, src image.Image, angle, length float64) error {
+	if dst == nil {
+		return errors.New("graphics: dst is nil")
+	}
+	if src == nil {
+		return errors.New("graphics: src is nil")
+	}
+	if length < 0 {
+		return errors.New("graphics: length is negative")
+	}
+
+	n := int(math.Ceil(length)) + 1
+	sin, cos := math.Sincos(angle)
+	return pathBlur(dst, src, n, func(x, y float64, i int) (float64, float64) {
+		if n == 1 {
+			return x, y
+		}
+		d := length * (float64(i)/float64(n-1) - 0.5)
+		return x + d*cos, y + d*sin
+	})
+}
+
+// pathFunc returns the i'th sample point of the path through (x, y).
+type pathFunc func(x, y float64, i int) (float64, float64)
+
+// pathBlur sets each pixel of dst to the mean of n samples of src, taken at
+// the points along path with bilinear interpolation. Samples outside src are
+// left out of the mean.
+func pathBlur(dst draw.Image, src image.Image, n int, path pathFunc) error {
+	srcb := src.Bounds()
+	b := dst.Bounds()
+	srcRGBA, srcOk := src.(*image.RGBA)
+	interpRGBA, interpOk := interp.Bilinear.(interp.RGBA)
+	dstRGBA, dstOk := dst.(*image.RGBA)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			var r, g, bl, a float64
+			count := 0
+			fx, fy := float64(x)+0.5, float64(y)+0.5
+			for i := 0; i < n; i++ {
+				sx, sy := path(fx, fy, i)
+				if !inBounds(srcb, sx, sy) {
+					continue
+				}
+				if srcOk && interpOk {
+					c := interpRGBA.RGBA(srcRGBA, sx, sy)
+					r += float64(c.R) * 0x101
+					g += float64(c.G) * 0x101
+					bl += float64(c.B) * 0x101
+					a += float64(c.A) * 0x101
+				} else {
+					cr, cg, cb, ca := interp.Bilinear.Interp(src, sx, sy).RGBA()
+					r += float64(cr)
+					g += float64(cg)
+					bl += float64(cb)
+					a += float64(ca)
+				}
+				count++
+			}
+			if count == 0 {
+				continue
+			}
+
+			f := 1 / float64(count)
+			if dstOk {
+				off := (y-dstRGBA.Rect.Min.Y)*dstRGBA.Stride + (x-dstRGBA.Rect.Min.X)*4
+				dstRGBA.Pix[off+0] = uint8(r*f/0x101 + 0.5)
+				dstRGBA.Pix[off+1] = uint8(g*f/0x101 + 0.5)
+				dstRGBA.Pix[off+2] = uint8(bl*f/0x101 + 0.5)
+				dstRGBA.Pix[off+3] = uint8(a*f/0x101 + 0.5)
+				continue
+			}
+			dst.Set(x, y, color.RGBA64{
+				uint16(r*f + 0.5),
+				uint16(g*f + 0.5),
+				uint16(bl*f + 0.5),
+				uint16(a*f + 0.5),
+			})
+		}
+	}
+	return nil
+}
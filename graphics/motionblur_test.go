@@ -0,0 +1,67 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"github.com/BurntSushi/graphics-go/graphics/graphicstest"
+	"image"
+	"math"
+	"testing"
+)
+
+var motionBlurSrc = []uint8{
+	0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0xff, 0x00, 0x00,
+	0x00, 0x00, 0xff, 0x00, 0x00,
+	0x00, 0x00, 0xff, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00,
+}
+
+func TestMotionBlurHorizontal(t *testing.T) {
+	src := graphicstest.MakeRGBA(motionBlurSrc, 5)
+	dst := image.NewRGBA(src.Bounds())
+	if err := MotionBlur(dst, src, 0, 2); err != nil {
+		t.Fatal(err)
+	}
+	want := graphicstest.MakeRGBA([]uint8{
+		0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x55, 0x55, 0x55, 0x00,
+		0x00, 0x55, 0x55, 0x55, 0x00,
+		0x00, 0x55, 0x55, 0x55, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00,
+	}, 5)
+	if err := graphicstest.ImageWithinTolerance(dst, want, 0x101); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMotionBlurAlong(t *testing.T) {
+	// Motion along the line leaves its middle unchanged.
+	src := graphicstest.MakeRGBA(motionBlurSrc, 5)
+	dst := image.NewRGBA(src.Bounds())
+	if err := MotionBlur(dst, src, math.Pi/2, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := dst.RGBAAt(2, 2).R; got != 0xff {
+		t.Errorf("got %#x want 0xff", got)
+	}
+	if got := dst.RGBAAt(1, 2).R; got != 0 {
+		t.Errorf("got %#x want 0", got)
+	}
+}
+
+func TestMotionBlurZero(t *testing.T) {
+	src := graphicstest.MakeRGBA(motionBlurSrc, 5)
+	dst := image.NewNRGBA(src.Bounds())
+	if err := MotionBlur(dst, src, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := graphicstest.ImageWithinTolerance(dst, src, 0); err != nil {
+		t.Error(err)
+	}
+	if err := MotionBlur(dst, src, 1, -1); err == nil {
+		t.Error("negative length: got nil error")
+	}
+}
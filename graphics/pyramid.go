@@ -0,0 +1,120 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// PyramidOptions are the pyramid parameters.
+// SmartCrop, if non-nil, chooses the crop region of each output as
+// SmartThumbnail does. Otherwise, the outputs are centered like Thumbnail.
+type PyramidOptions struct {
+	SmartCrop *SmartCropOptions
+}
+
+// Pyramid produces a thumbnail of src for each of sizes, reading src once.
+// It builds a mipmap of src by repeatedly halving it with a box filter, and
+// then scales and crops each thumbnail from the smallest level that is at
+// least as large as it needs.
+func Pyramid(src image.Image, sizes []image.Point, opt *PyramidOptions) ([]*image.RGBA, error) {
+	if src == nil {
+		return nil, errors.New("graphics: src is nil")
+	}
+	for _, s := range sizes {
+		if s.X <= 0 || s.Y <= 0 {
+			return nil, fmt.Errorf("graphics: invalid pyramid size %v", s)
+		}
+	}
+
+	// need is the smallest scaled size, before cropping, of any output.
+	sb := src.Bounds()
+	var need image.Point
+	for i, s := range sizes {
+		b := thumbnailSize(sb, image.Rectangle{image.ZP, s})
+		if i == 0 || b.X < need.X {
+			need.X = b.X
+		}
+		if i == 0 || b.Y < need.Y {
+			need.Y = b.Y
+		}
+	}
+
+	levels := []image.Image{src}
+	for m := src; ; {
+		b := m.Bounds()
+		if b.Dx()/2 < need.X || b.Dy()/2 < need.Y {
+			break
+		}
+		m = halve(m)
+		levels = append(levels, m)
+	}
+
+	res := make([]*image.RGBA, len(sizes))
+	for i, s := range sizes {
+		dst := image.NewRGBA(image.Rect(0, 0, s.X, s.Y))
+		b := thumbnailSize(sb, dst.Bounds())
+
+		// Levels shrink, so the last large enough level is the smallest.
+		m := levels[0]
+		for _, l := range levels[1:] {
+			lb := l.Bounds()
+			if lb.Dx() < b.X || lb.Dy() < b.Y {
+				break
+			}
+			m = l
+		}
+
+		var err error
+		if opt != nil && opt.SmartCrop != nil {
+			err = SmartThumbnail(dst, m, opt.SmartCrop)
+		} else {
+			err = Thumbnail(dst, m)
+		}
+		if err != nil {
+			return nil, err
+		}
+		res[i] = dst
+	}
+	return res, nil
+}
+
+// halve returns src scaled to half its size with a 2x2 box filter. An odd
+// final row or column is averaged over the pixels that exist.
+func halve(src image.Image) *image.RGBA {
+	sb := src.Bounds()
+	m, ok := src.(*image.RGBA)
+	if !ok {
+		m = image.NewRGBA(sb)
+		draw.Draw(m, sb, src, sb.Min, draw.Src)
+	}
+
+	db := image.Rect(0, 0, (sb.Dx()+1)/2, (sb.Dy()+1)/2)
+	dst := image.NewRGBA(db)
+	for y := db.Min.Y; y < db.Max.Y; y++ {
+		for x := db.Min.X; x < db.Max.X; x++ {
+			var r, g, b, a, n int
+			for sy := sb.Min.Y + 2*y; sy < sb.Min.Y+2*y+2 && sy < sb.Max.Y; sy++ {
+				for sx := sb.Min.X + 2*x; sx < sb.Min.X+2*x+2 && sx < sb.Max.X; sx++ {
+					off := (sy-m.Rect.Min.Y)*m.Stride + (sx-m.Rect.Min.X)*4
+					r += int(m.Pix[off+0])
+					g += int(m.Pix[off+1])
+					b += int(m.Pix[off+2])
+					a += int(m.Pix[off+3])
+					n++
+				}
+			}
+			off := y*dst.Stride + x*4
+			dst.Pix[off+0] = uint8((r + n/2) / n)
+			dst.Pix[off+1] = uint8((g + n/2) / n)
+			dst.Pix[off+2] = uint8((b + n/2) / n)
+			dst.Pix[off+3] = uint8((a + n/2) / n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,90 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"github.com/BurntSushi/graphics-go/graphics/graphicstest"
+	"image"
+	"testing"
+
+	_ "image/png"
+)
+
+func TestHalve(t *testing.T) {
+	src := graphicstest.MakeRGBA([]uint8{
+		0x10, 0x30, 0x80,
+		0x30, 0x10, 0x80,
+		0x40, 0x40, 0x20,
+	}, 3)
+	dst := halve(src)
+	want := graphicstest.MakeRGBA([]uint8{
+		0x20, 0x80,
+		0x40, 0x20,
+	}, 2)
+	if err := graphicstest.ImageWithinTolerance(dst, want, 0); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPyramidGopher(t *testing.T) {
+	src, err := graphicstest.LoadImage("../testdata/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sizes := []image.Point{{80, 80}, {50, 150}, {400, 600}, {20, 10}}
+	res, err := Pyramid(src, sizes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != len(sizes) {
+		t.Fatalf("got %d images want %d", len(res), len(sizes))
+	}
+	for i, s := range sizes {
+		if got := res[i].Bounds().Size(); got != s {
+			t.Errorf("%d: got size %v want %v", i, got, s)
+		}
+	}
+
+	// Sizes that need no halving are produced straight from src.
+	want := image.NewRGBA(image.Rect(0, 0, 400, 600))
+	if err := Thumbnail(want, src); err != nil {
+		t.Fatal(err)
+	}
+	if err := graphicstest.ImageWithinTolerance(res[2], want, 0); err != nil {
+		t.Error(err)
+	}
+
+	// Box filtering averages out detail that bilinear sampling skips, so
+	// compare the two after blurring away the high frequencies.
+	for i, name := range []string{"80x80", "50x150"} {
+		cmp, err := graphicstest.LoadImage("../testdata/gopher-thumb-" + name + ".png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := image.NewRGBA(res[i].Bounds())
+		want := image.NewRGBA(cmp.Bounds())
+		opt := &BlurOptions{StdDev: 2}
+		if err := Blur(got, res[i], opt); err != nil {
+			t.Fatal(err)
+		}
+		if err := Blur(want, cmp, opt); err != nil {
+			t.Fatal(err)
+		}
+		if err := graphicstest.ImageWithinTolerance(got, want, 0x1800); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestPyramidInvalid(t *testing.T) {
+	if _, err := Pyramid(nil, []image.Point{{1, 1}}, nil); err == nil {
+		t.Error("nil src: got nil error")
+	}
+	src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	if _, err := Pyramid(src, []image.Point{{0, 1}}, nil); err == nil {
+		t.Error("zero size: got nil error")
+	}
+}
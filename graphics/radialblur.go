@@ -0,0 +1,99 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// RadialBlurOptions are the parameters of ZoomBlur and SpinBlur.
+// Samples is the number of samples averaged for each pixel, higher is
+// smoother and slower. If zero, it is set so that the longest path is
+// sampled about once per pixel.
+type RadialBlurOptions struct {
+	Samples int
+}
+
+// ZoomBlur produces a blurred version of the image, as if the camera zoomed
+// in towards center during exposure. Each pixel is the mean of samples along
+// the ray from the pixel towards center, covering the fraction amount of the
+// distance between them.
+func ZoomBlur(dst draw.Image, src image.Image, center image.Point, amount float64, opt *RadialBlurOptions) error {
+	if dst == nil {
+		return errors.New("graphics: dst is nil")
+	}
+	if src == nil {
+		return errors.New("graphics: src is nil")
+	}
+	if amount < 0 || amount > 1 {
+		return errors.New("graphics: zoom amount not in [0, 1]")
+	}
+
+	cx, cy := float64(center.X)+0.5, float64(center.Y)+0.5
+	n := radialSamples(opt, amount*maxRadius(dst.Bounds(), cx, cy))
+	return pathBlur(dst, src, n, func(x, y float64, i int) (float64, float64) {
+		if n == 1 {
+			return x, y
+		}
+		s := 1 - amount*float64(i)/float64(n-1)
+		return cx + (x-cx)*s, cy + (y-cy)*s
+	})
+}
+
+// SpinBlur produces a blurred version of the image, as if the camera rotated
+// about center during exposure. Each pixel is the mean of samples along the
+// arc around center through the pixel, spanning angle radians.
+func SpinBlur(dst draw.Image, src image.Image, center image.Point, angle float64, opt *RadialBlurOptions) error {
+	if dst == nil {
+		return errors.New("graphics: dst is nil")
+	}
+	if src == nil {
+		return errors.New("graphics: src is nil")
+	}
+	if angle < 0 {
+		return errors.New("graphics: spin angle is negative")
+	}
+
+	cx, cy := float64(center.X)+0.5, float64(center.Y)+0.5
+	n := radialSamples(opt, angle*maxRadius(dst.Bounds(), cx, cy))
+
+	// Precompute the rotation of each sample.
+	sin := make([]float64, n)
+	cos := make([]float64, n)
+	for i := range sin {
+		a := 0.0
+		if n > 1 {
+			a = angle * (float64(i)/float64(n-1) - 0.5)
+		}
+		sin[i], cos[i] = math.Sincos(a)
+	}
+	return pathBlur(dst, src, n, func(x, y float64, i int) (float64, float64) {
+		dx, dy := x-cx, y-cy
+		return cx + dx*cos[i] - dy*sin[i], cy + dx*sin[i] + dy*cos[i]
+	})
+}
+
+// radialSamples returns the number of samples to take along paths that are
+// at most length pixels long.
+func radialSamples(opt *RadialBlurOptions, length float64) int {
+	if opt != nil && opt.Samples > 0 {
+		return opt.Samples
+	}
+	return int(math.Ceil(length)) + 1
+}
+
+// maxRadius returns the distance from (cx, cy) to the farthest corner of b.
+func maxRadius(b image.Rectangle, cx, cy float64) float64 {
+	r := 0.0
+	for _, p := range []image.Point{b.Min, b.Max, {b.Min.X, b.Max.Y}, {b.Max.X, b.Min.Y}} {
+		if d := math.Hypot(float64(p.X)-cx, float64(p.Y)-cy); d > r {
+			r = d
+		}
+	}
+	return r
+}
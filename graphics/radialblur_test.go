@@ -0,0 +1,92 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"github.com/BurntSushi/graphics-go/graphics/graphicstest"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// horizontalLine returns a 9x9 image with a white line along row 4.
+func horizontalLine() *image.RGBA {
+	m := image.NewRGBA(image.Rect(0, 0, 9, 9))
+	for x := 0; x < 9; x++ {
+		m.SetRGBA(x, 4, color.RGBA{0xff, 0xff, 0xff, 0xff})
+	}
+	return m
+}
+
+func TestZoomBlur(t *testing.T) {
+	src := horizontalLine()
+	dst := image.NewRGBA(src.Bounds())
+	if err := ZoomBlur(dst, src, image.Pt(4, 4), 0.5, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rays along the line stay on it, and rays from off the line only
+	// meet it at the center.
+	if got := dst.RGBAAt(8, 4).R; got != 0xff {
+		t.Errorf("on line: got %#x want 0xff", got)
+	}
+	if got := dst.RGBAAt(8, 0).R; got != 0 {
+		t.Errorf("off line: got %#x want 0", got)
+	}
+}
+
+func TestZoomBlurZero(t *testing.T) {
+	src, err := graphicstest.LoadImage("../testdata/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := image.NewRGBA(src.Bounds())
+	if err := ZoomBlur(dst, src, image.Pt(200, 300), 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := graphicstest.ImageWithinTolerance(dst, src, 0x101); err != nil {
+		t.Error(err)
+	}
+	if err := ZoomBlur(dst, src, image.ZP, 1.5, nil); err == nil {
+		t.Error("amount 1.5: got nil error")
+	}
+}
+
+func TestSpinBlur(t *testing.T) {
+	src := horizontalLine()
+	dst := image.NewRGBA(src.Bounds())
+	opt := &RadialBlurOptions{Samples: 9}
+	if err := SpinBlur(dst, src, image.Pt(4, 4), math.Pi/2, opt); err != nil {
+		t.Fatal(err)
+	}
+
+	// The center does not move, and the ends of the line are spread along
+	// their arcs.
+	if got := dst.RGBAAt(4, 4).R; got != 0xff {
+		t.Errorf("center: got %#x want 0xff", got)
+	}
+	if got := dst.RGBAAt(8, 4).R; got == 0 || got == 0xff {
+		t.Errorf("end: got %#x", got)
+	}
+	if got := dst.RGBAAt(8, 2).R; got == 0 {
+		t.Errorf("arc: got %#x", got)
+	}
+}
+
+func TestSpinBlurUniform(t *testing.T) {
+	src := graphicstest.MakeRGBA([]uint8{
+		0x80, 0x80, 0x80,
+		0x80, 0x80, 0x80,
+		0x80, 0x80, 0x80,
+	}, 3)
+	dst := image.NewRGBA(src.Bounds())
+	if err := SpinBlur(dst, src, image.Pt(0, 0), 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := graphicstest.ImageWithinTolerance(dst, src, 0); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+)
+
+// Median produces a version of the image where each channel of each pixel
+// is the median of that channel over the (2*radius+1)x(2*radius+1) square
+// around it. It removes salt-and-pepper noise while keeping edges sharp.
+func Median(dst draw.Image, src image.Image, radius int) error {
+	return Percentile(dst, src, radius, 0.5)
+}
+
+// Min is like Median, but takes the smallest value in the square.
+func Min(dst draw.Image, src image.Image, radius int) error {
+	return Percentile(dst, src, radius, 0)
+}
+
+// Max is like Median, but takes the largest value in the square.
+func Max(dst draw.Image, src image.Image, radius int) error {
+	return Percentile(dst, src, radius, 1)
+}
+
+// Percentile is like Median, but takes the value at fraction p, in the
+// range [0, 1], of the sorted values in the square.
+//
+// Pixels beyond the edges of the image repeat the nearest edge pixel. The
+// cost does not depend on radius. See S. Perreault, P. Hébert. Median
+// Filtering in Constant Time, IEEE Trans. Image Processing, 2007.
+func Percentile(dst draw.Image, src image.Image, radius int, p float64) error {
+	if dst == nil {
+		return errors.New("graphics: dst is nil")
+	}
+	if src == nil {
+		return errors.New("graphics: src is nil")
+	}
+	if radius < 0 {
+		return errors.New("graphics: radius is negative")
+	}
+	if p < 0 || p > 1 {
+		return errors.New("graphics: percentile not in [0, 1]")
+	}
+
+	b := dst.Bounds()
+	if b.Empty() {
+		return nil
+	}
+	m := image.NewRGBA(b)
+	draw.Draw(m, b, src, b.Min, draw.Src)
+	res, ok := dst.(*image.RGBA)
+	if !ok {
+		res = image.NewRGBA(b)
+	}
+
+	size := 2*radius + 1
+	rank := int(p*float64(size*size-1) + 0.5)
+	for c := 0; c < 4; c++ {
+		rankChannel(res, m, c, radius, rank)
+	}
+
+	clampToAlpha(res)
+
+	if !ok {
+		draw.Draw(dst, b, res, b.Min, draw.Src)
+	}
+	return nil
+}
+
+// clampToAlpha clamps each color channel of m to its alpha, as colors are
+// premultiplied.
+func clampToAlpha(m *image.RGBA) {
+	b := m.Bounds()
+	for y := 0; y < b.Dy(); y++ {
+		pix := m.Pix[y*m.Stride : y*m.Stride+b.Dx()*4]
+		for i := 0; i < len(pix); i += 4 {
+			for c := 0; c < 3; c++ {
+				if pix[i+c] > pix[i+3] {
+					pix[i+c] = pix[i+3]
+				}
+			}
+		}
+	}
+}
+
+// rankHist is a two level histogram of 8-bit values. coarse[i] counts the
+// values in fine[16*i : 16*i+16].
+type rankHist struct {
+	coarse [16]int32
+	fine   [256]int32
+}
+
+// rankChannel sets channel c of each pixel in dst to the value of rank,
+// counting from zero, among channel c of the square around it in src. dst
+// and src have the same bounds.
+func rankChannel(dst, src *image.RGBA, c, radius, rank int) {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	at := func(x, y int) uint8 {
+		x = clampInt(x, 0, w-1)
+		y = clampInt(y, 0, h-1)
+		return src.Pix[y*src.Stride+x*4+c]
+	}
+
+	// cols[x] is the histogram of the column x over the rows in the square.
+	// Start with the square above the first row.
+	cols := make([]rankHist, w)
+	for x := range cols {
+		for y := -radius - 1; y < radius; y++ {
+			v := at(x, y)
+			cols[x].coarse[v>>4]++
+			cols[x].fine[v]++
+		}
+	}
+
+	col := func(x int) *rankHist { return &cols[clampInt(x, 0, w-1)] }
+	for y := 0; y < h; y++ {
+		// Move each column down a row.
+		for x := range cols {
+			add, sub := at(x, y+radius), at(x, y-radius-1)
+			cols[x].coarse[add>>4]++
+			cols[x].fine[add]++
+			cols[x].coarse[sub>>4]--
+			cols[x].fine[sub]--
+		}
+
+		// The coarse kernel histogram is kept up to date as it moves right.
+		// Each segment of the fine one is only brought up to date when it
+		// is searched, from the position in synced.
+		var kernel rankHist
+		var synced [16]int
+		for i := range synced {
+			synced[i] = -1
+		}
+		for x := -radius; x <= radius; x++ {
+			ch := col(x)
+			for i := range kernel.coarse {
+				kernel.coarse[i] += ch.coarse[i]
+			}
+		}
+
+		for x := 0; x < w; x++ {
+			if x > 0 {
+				add, sub := col(x+radius), col(x-radius-1)
+				for i := range kernel.coarse {
+					kernel.coarse[i] += add.coarse[i] - sub.coarse[i]
+				}
+			}
+
+			// Find the coarse segment holding rank.
+			n, seg := 0, 0
+			for ; seg < 15; seg++ {
+				if n+int(kernel.coarse[seg]) > rank {
+					break
+				}
+				n += int(kernel.coarse[seg])
+			}
+
+			syncSegment(&kernel, seg, synced[seg], x, radius, col)
+			synced[seg] = x
+
+			v := seg * 16
+			for ; v < seg*16+15; v++ {
+				if n+int(kernel.fine[v]) > rank {
+					break
+				}
+				n += int(kernel.fine[v])
+			}
+			dst.Pix[y*dst.Stride+x*4+c] = uint8(v)
+		}
+	}
+}
+
+// syncSegment brings segment seg of the fine kernel histogram, last synced
+// at position from, up to date at position to. A from before the first
+// column means that the segment has not been computed.
+func syncSegment(k *rankHist, seg, from, to, radius int, col func(int) *rankHist) {
+	lo, hi := seg*16, seg*16+16
+	if from < 0 || to-from > 2*radius+1 {
+		for v := lo; v < hi; v++ {
+			k.fine[v] = 0
+		}
+		for x := to - radius; x <= to+radius; x++ {
+			ch := col(x)
+			for v := lo; v < hi; v++ {
+				k.fine[v] += ch.fine[v]
+			}
+		}
+		return
+	}
+	for x := from + 1; x <= to; x++ {
+		add, sub := col(x+radius), col(x-radius-1)
+		for v := lo; v < hi; v++ {
+			k.fine[v] += add.fine[v] - sub.fine[v]
+		}
+	}
+}
+
+// clampInt clamps x to the range [x0, x1].
+func clampInt(x, x0, x1 int) int {
+	if x < x0 {
+		return x0
+	}
+	if x > x1 {
+		return x1
+	}
+	return x
+}
@@ -0,0 +1,132 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"github.com/BurntSushi/graphics-go/graphics/graphicstest"
+	"image"
+	"image/color"
+	"sort"
+	"testing"
+
+	_ "image/png"
+)
+
+// rankExact sorts the square around each pixel, for reference.
+func rankExact(src *image.RGBA, radius int, p float64) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(b)
+	size := 2*radius + 1
+	rank := int(p*float64(size*size-1) + 0.5)
+	vals := make([]int, 0, size*size)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			var c [4]uint8
+			for ch := range c {
+				vals = vals[:0]
+				for cy := y - radius; cy <= y+radius; cy++ {
+					for cx := x - radius; cx <= x+radius; cx++ {
+						sx := clampInt(cx, b.Min.X, b.Max.X-1)
+						sy := clampInt(cy, b.Min.Y, b.Max.Y-1)
+						vals = append(vals, int(src.Pix[src.PixOffset(sx, sy)+ch]))
+					}
+				}
+				sort.Ints(vals)
+				c[ch] = uint8(vals[rank])
+			}
+			dst.SetRGBA(x, y, color.RGBA{c[0], c[1], c[2], c[3]})
+		}
+	}
+	return dst
+}
+
+// noisyRGBA returns an image of pseudo-random opaque pixels.
+func noisyRGBA(b image.Rectangle) *image.RGBA {
+	m := image.NewRGBA(b)
+	seed := uint32(1)
+	for i := range m.Pix {
+		seed = seed*1103515245 + 12345
+		m.Pix[i] = uint8(seed >> 16)
+		if i%4 == 3 {
+			m.Pix[i] = 0xff
+		}
+	}
+	return m
+}
+
+func TestPercentileExact(t *testing.T) {
+	src := noisyRGBA(image.Rect(0, 0, 23, 17))
+	for _, radius := range []int{0, 1, 2, 5} {
+		for _, p := range []float64{0, 0.25, 0.5, 1} {
+			dst := image.NewRGBA(src.Bounds())
+			if err := Percentile(dst, src, radius, p); err != nil {
+				t.Fatal(err)
+			}
+			want := rankExact(src, radius, p)
+			if err := graphicstest.ImageWithinTolerance(dst, want, 0); err != nil {
+				t.Errorf("radius %d, p %v: %v", radius, p, err)
+			}
+		}
+	}
+}
+
+func TestMedianSaltAndPepper(t *testing.T) {
+	src := graphicstest.MakeRGBA([]uint8{
+		0x80, 0x80, 0x80, 0x80, 0x80,
+		0x80, 0xff, 0x80, 0x80, 0x80,
+		0x80, 0x80, 0x80, 0x00, 0x80,
+		0x80, 0x80, 0x80, 0x80, 0x80,
+	}, 5)
+	dst := image.NewRGBA(src.Bounds())
+	if err := Median(dst, src, 1); err != nil {
+		t.Fatal(err)
+	}
+	want := graphicstest.MakeRGBA([]uint8{
+		0x80, 0x80, 0x80, 0x80, 0x80,
+		0x80, 0x80, 0x80, 0x80, 0x80,
+		0x80, 0x80, 0x80, 0x80, 0x80,
+		0x80, 0x80, 0x80, 0x80, 0x80,
+	}, 5)
+	if err := graphicstest.ImageWithinTolerance(dst, want, 0); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMinMax(t *testing.T) {
+	src := graphicstest.MakeRGBA([]uint8{
+		0x10, 0x20, 0x30,
+		0x40, 0x50, 0x60,
+	}, 3)
+	dst := image.NewNRGBA(src.Bounds())
+	if err := Min(dst, src, 1); err != nil {
+		t.Fatal(err)
+	}
+	want := graphicstest.MakeRGBA([]uint8{
+		0x10, 0x10, 0x20,
+		0x10, 0x10, 0x20,
+	}, 3)
+	if err := graphicstest.ImageWithinTolerance(dst, want, 0); err != nil {
+		t.Error(err)
+	}
+
+	if err := Max(dst, src, 1); err != nil {
+		t.Fatal(err)
+	}
+	want = graphicstest.MakeRGBA([]uint8{
+		0x50, 0x60, 0x60,
+		0x50, 0x60, 0x60,
+	}, 3)
+	if err := graphicstest.ImageWithinTolerance(dst, want, 0); err != nil {
+		t.Error(err)
+	}
+}
+
+func BenchmarkMedian400x400x20(b *testing.B) {
+	src := noisyRGBA(image.Rect(0, 0, 400, 400))
+	dst := image.NewRGBA(src.Bounds())
+	for i := 0; i < b.N; i++ {
+		Median(dst, src, 20)
+	}
+}
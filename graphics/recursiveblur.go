@@ -0,0 +1,118 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// recursiveCoef are the coefficients of a fourth order recursive Gaussian
+// filter, made of a causal and an anti-causal part.
+type recursiveCoef struct {
+	// n and m are the causal and anti-causal numerator coefficients.
+	n, m [4]float64
+	// d is the denominator coefficients, shared by both parts.
+	d [4]float64
+	// nEdge and mEdge are the steady-state responses to a constant 1.
+	nEdge, mEdge float64
+	// scale normalizes the filter to sum to 1.
+	scale float64
+}
+
+// newRecursiveCoef returns the filter coefficients for standard deviation
+// sd.
+//
+// See R. Deriche. Recursively implementing the Gaussian and its
+// derivatives, INRIA Research Report 1893, 1993.
+func newRecursiveCoef(sd float64) recursiveCoef {
+	const (
+		a0, a1 = 1.680, 3.735
+		b0, b1 = 1.783, 1.723
+		c0, c1 = -0.6803, -0.2598
+		w0, w1 = 0.6318, 1.997
+	)
+	sin0, cos0 := math.Sincos(w0 / sd)
+	sin1, cos1 := math.Sincos(w1 / sd)
+	e0, e1 := math.Exp(-b0/sd), math.Exp(-b1/sd)
+
+	var c recursiveCoef
+	c.n[0] = a0 + c0
+	c.n[1] = e1*(c1*sin1-(c0+2*a0)*cos1) + e0*(a1*sin0-(2*c0+a0)*cos0)
+	c.n[2] = 2*e0*e1*((a0+c0)*cos1*cos0-a1*cos1*sin0-c1*cos0*sin1) +
+		c0*e0*e0 + a0*e1*e1
+	c.n[3] = e1*e0*e0*(c1*sin1-c0*cos1) + e0*e1*e1*(a1*sin0-a0*cos0)
+	c.d[0] = -2*e1*cos1 - 2*e0*cos0
+	c.d[1] = 4*cos1*cos0*e0*e1 + e1*e1 + e0*e0
+	c.d[2] = -2*cos0*e0*e1*e1 - 2*cos1*e1*e0*e0
+	c.d[3] = e0 * e0 * e1 * e1
+	for i := 0; i < 3; i++ {
+		c.m[i] = c.n[i+1] - c.d[i]*c.n[0]
+	}
+	c.m[3] = -c.d[3] * c.n[0]
+
+	sn, sm, sd1 := 0.0, 0.0, 1.0
+	for i := 0; i < 4; i++ {
+		sn += c.n[i]
+		sm += c.m[i]
+		sd1 += c.d[i]
+	}
+	c.nEdge, c.mEdge = sn/sd1, sm/sd1
+	c.scale = 1 / (c.nEdge + c.mEdge)
+	return c
+}
+
+// line filters the n values of v, step apart. Values beyond either end of
+// the line repeat the value at that end.
+func (c *recursiveCoef) line(v []float64, n, step int, buf []float64) {
+	// Causal pass, from the left, into buf.
+	x := [4]float64{v[0], v[0], v[0], v[0]}
+	y := [4]float64{}
+	for i := range y {
+		y[i] = c.nEdge * v[0]
+	}
+	for i := 0; i < n; i++ {
+		x = [4]float64{v[i*step], x[0], x[1], x[2]}
+		yi := c.n[0]*x[0] + c.n[1]*x[1] + c.n[2]*x[2] + c.n[3]*x[3] -
+			c.d[0]*y[0] - c.d[1]*y[1] - c.d[2]*y[2] - c.d[3]*y[3]
+		y = [4]float64{yi, y[0], y[1], y[2]}
+		buf[i] = yi
+	}
+
+	// Anti-causal pass, from the right, added to buf.
+	last := v[(n-1)*step]
+	x = [4]float64{last, last, last, last}
+	for i := range y {
+		y[i] = c.mEdge * last
+	}
+	for i := n - 1; i >= 0; i-- {
+		yi := c.m[0]*x[0] + c.m[1]*x[1] + c.m[2]*x[2] + c.m[3]*x[3] -
+			c.d[0]*y[0] - c.d[1]*y[1] - c.d[2]*y[2] - c.d[3]*y[3]
+		y = [4]float64{yi, y[0], y[1], y[2]}
+		x = [4]float64{v[i*step], x[0], x[1], x[2]}
+		v[i*step] = (buf[i] + yi) * c.scale
+	}
+}
+
+// recursiveBlur blurs src into dst with a recursive Gaussian filter of
+// standard deviation sdx horizontally and sdy vertically.
+func recursiveBlur(dst draw.Image, src image.Image, sdx, sdy float64) error {
+	b := dst.Bounds()
+	if b.Empty() {
+		return nil
+	}
+	w, h := b.Dx(), b.Dy()
+	buf := loadRGBA(src, b)
+	cx, cy := newRecursiveCoef(sdx), newRecursiveCoef(sdy)
+	tmp := make([]float64, w+h)
+	eachLine(buf, w, h, func(v []float64, n, step int) {
+		cx.line(v, n, step, tmp)
+	}, func(v []float64, n, step int) {
+		cy.line(v, n, step, tmp)
+	})
+	storeRGBA(dst, buf)
+	return nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"github.com/BurntSushi/graphics-go/graphics/graphicstest"
+	"image"
+	"testing"
+
+	_ "image/png"
+)
+
+func TestBlurRecursiveGopher(t *testing.T) {
+	src, err := graphicstest.LoadImage("../testdata/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := src.Bounds()
+
+	for _, sd := range []float64{0.8, 1.1, 3, 8} {
+		dst := image.NewRGBA(b)
+		opt := &BlurOptions{StdDev: sd, Method: BlurRecursive}
+		if err := Blur(dst, src, opt); err != nil {
+			t.Fatal(err)
+		}
+		cmp := image.NewRGBA(b)
+		if err := Blur(cmp, src, &BlurOptions{StdDev: sd}); err != nil {
+			t.Fatal(err)
+		}
+
+		// Compare away from the borders, which are extended differently.
+		inner := b.Inset(int(4*sd) + 2)
+		err := graphicstest.ImageWithinTolerance(dst.SubImage(inner), cmp.SubImage(inner), 0x101)
+		if err != nil {
+			t.Errorf("sd %v: %v", sd, err)
+		}
+	}
+}
+
+func TestBlurRecursiveUniform(t *testing.T) {
+	src := graphicstest.MakeRGBA([]uint8{
+		0x80, 0x80, 0x80,
+		0x80, 0x80, 0x80,
+	}, 3)
+	dst := image.NewRGBA(src.Bounds())
+	if err := Blur(dst, src, &BlurOptions{StdDev: 20, Method: BlurRecursive}); err != nil {
+		t.Fatal(err)
+	}
+	if err := graphicstest.ImageWithinTolerance(dst, src, 0); err != nil {
+		t.Error(err)
+	}
+}
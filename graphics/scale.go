@@ -27,5 +27,7 @@ func Scale(dst draw.Image, src image.Image) error {
 	}
 	sx := float64(b.Dx()) / float64(srcb.Dx())
 	sy := float64(b.Dy()) / float64(srcb.Dy())
-	return I.Scale(sx, sy).Transform(dst, src, interp.Bilinear)
+	a := I.Translate(-float64(srcb.Min.X), -float64(srcb.Min.Y)).Scale(sx, sy)
+	a = a.Translate(float64(b.Min.X), float64(b.Min.Y))
+	return a.Transform(dst, src, interp.Bilinear)
 }
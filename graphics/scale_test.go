@@ -5,6 +5,7 @@
 package graphics
 
 import (
+	"bytes"
 	"github.com/BurntSushi/graphics-go/graphics/graphicstest"
 	"image"
 	"testing"
@@ -151,3 +152,23 @@ func TestScaleGopher(t *testing.T) {
 		return
 	}
 }
+
+func TestScaleOffset(t *testing.T) {
+	for _, oc := range scaleOneColorTests {
+		src := oc.newSrc()
+		dst := oc.newDst()
+		if err := Scale(dst, src); err != nil {
+			t.Fatal(err)
+		}
+
+		// Move both images away from the origin.
+		srcOff := &image.RGBA{Pix: src.Pix, Stride: src.Stride, Rect: src.Rect.Add(image.Pt(3, 5))}
+		dstOff := image.NewRGBA(dst.Rect.Add(image.Pt(7, 2)))
+		if err := Scale(dstOff, srcOff); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(dst.Pix, dstOff.Pix) {
+			t.Errorf("%s: got %v want %v", oc.desc, dstOff.Pix, dst.Pix)
+		}
+	}
+}
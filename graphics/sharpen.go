@@ -0,0 +1,100 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+)
+
+// DefaultSharpenOptions are the sharpening parameters used when none are
+// provided.
+var DefaultSharpenOptions = SharpenOptions{Amount: 1, Radius: 1}
+
+// SharpenOptions are the sharpening parameters.
+// Amount is the strength of the sharpening, where 1 doubles the contrast
+// of the detail that is removed by blurring.
+// Radius is the standard deviation of the blur, higher sharpens coarser
+// detail.
+// Threshold is the smallest difference, in the range [0, 255], between a
+// pixel and its blurred value that is sharpened. It avoids amplifying noise
+// in smooth areas.
+type SharpenOptions struct {
+	Amount    float64
+	Radius    float64
+	Threshold float64
+}
+
+// Sharpen produces a sharpened version of the image, using an unsharp mask.
+// The difference between src and a Gaussian blur of src is scaled and added
+// back to src. Alpha is left unchanged.
+func Sharpen(dst draw.Image, src image.Image, opt *SharpenOptions) error {
+	if dst == nil {
+		return errors.New("graphics: dst is nil")
+	}
+	if src == nil {
+		return errors.New("graphics: src is nil")
+	}
+	if opt == nil {
+		opt = &DefaultSharpenOptions
+	}
+
+	b := dst.Bounds()
+	if b.Empty() {
+		return nil
+	}
+	blurred := image.NewRGBA(b)
+	if err := Blur(blurred, src, &BlurOptions{StdDev: opt.Radius}); err != nil {
+		return err
+	}
+
+	buf := loadRGBA(src, b)
+	for i := 0; i < len(buf); i += 4 {
+		a := buf[i+3]
+		for c := 0; c < 3; c++ {
+			d := buf[i+c] - float64(blurred.Pix[i+c])
+			if d <= opt.Threshold && d >= -opt.Threshold {
+				continue
+			}
+			// Colors are premultiplied, so may not exceed alpha.
+			buf[i+c] = clamp(buf[i+c]+opt.Amount*d, 0, a)
+		}
+	}
+	storeRGBA(dst, buf)
+	return nil
+}
+
+// HighPass produces the detail that a Gaussian blur of standard deviation
+// radius removes from src, offset by mid-gray so that it can be stored.
+// Alpha is left unchanged.
+func HighPass(dst draw.Image, src image.Image, radius float64) error {
+	if dst == nil {
+		return errors.New("graphics: dst is nil")
+	}
+	if src == nil {
+		return errors.New("graphics: src is nil")
+	}
+
+	b := dst.Bounds()
+	if b.Empty() {
+		return nil
+	}
+	blurred := image.NewRGBA(b)
+	if err := Blur(blurred, src, &BlurOptions{StdDev: radius}); err != nil {
+		return err
+	}
+
+	buf := loadRGBA(src, b)
+	for i := 0; i < len(buf); i += 4 {
+		a := buf[i+3]
+		for c := 0; c < 3; c++ {
+			d := buf[i+c] - float64(blurred.Pix[i+c])
+			buf[i+c] = clamp(a/2+d, 0, a)
+		}
+	}
+	storeRGBA(dst, buf)
+	return nil
+}
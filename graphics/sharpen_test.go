@@ -0,0 +1,72 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"github.com/BurntSushi/graphics-go/graphics/graphicstest"
+	"image"
+	"testing"
+)
+
+var sharpenStep = []uint8{
+	0x40, 0x40, 0x40, 0xc0, 0xc0, 0xc0,
+	0x40, 0x40, 0x40, 0xc0, 0xc0, 0xc0,
+}
+
+func TestSharpenStep(t *testing.T) {
+	src := graphicstest.MakeRGBA(sharpenStep, 6)
+	dst := image.NewRGBA(src.Bounds())
+	if err := Sharpen(dst, src, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The edge gains contrast, while the flat areas barely change.
+	for y := 0; y < 2; y++ {
+		if got := dst.RGBAAt(0, y).R; got < 0x3f || got > 0x40 {
+			t.Errorf("left: got %#x want 0x40", got)
+		}
+		if got := dst.RGBAAt(2, y).R; got >= 0x40 {
+			t.Errorf("dark edge: got %#x want < 0x40", got)
+		}
+		if got := dst.RGBAAt(3, y).R; got <= 0xc0 {
+			t.Errorf("light edge: got %#x want > 0xc0", got)
+		}
+		if got := dst.RGBAAt(5, y).R; got < 0xc0 || got > 0xc1 {
+			t.Errorf("right: got %#x want 0xc0", got)
+		}
+		if got := dst.RGBAAt(3, y).A; got != 0xff {
+			t.Errorf("alpha: got %#x want 0xff", got)
+		}
+	}
+}
+
+func TestSharpenThreshold(t *testing.T) {
+	src := graphicstest.MakeRGBA(sharpenStep, 6)
+	dst := image.NewRGBA(src.Bounds())
+	opt := &SharpenOptions{Amount: 2, Radius: 1, Threshold: 0x80}
+	if err := Sharpen(dst, src, opt); err != nil {
+		t.Fatal(err)
+	}
+	if err := graphicstest.ImageWithinTolerance(dst, src, 0); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHighPass(t *testing.T) {
+	src := graphicstest.MakeRGBA(sharpenStep, 6)
+	dst := image.NewRGBA(src.Bounds())
+	if err := HighPass(dst, src, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := dst.RGBAAt(0, 0).R; got != 0x7f {
+		t.Errorf("flat: got %#x want 0x7f", got)
+	}
+	if got := dst.RGBAAt(2, 0).R; got >= 0x7f {
+		t.Errorf("dark edge: got %#x want < 0x7f", got)
+	}
+	if got := dst.RGBAAt(3, 0).R; got <= 0x80 {
+		t.Errorf("light edge: got %#x want > 0x80", got)
+	}
+}
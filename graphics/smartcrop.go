@@ -0,0 +1,162 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"github.com/BurntSushi/graphics-go/graphics/detect"
+	"image"
+	"image/draw"
+)
+
+// SmartCropOptions are the smart cropping parameters.
+// Cascade, if non-nil, is used to find faces in the scaled image.
+// FaceWeight, EdgeWeight and SaturationWeight are the contributions of a
+// pixel that is part of a face, lies on an edge, or is strongly colored,
+// to the score of a crop window. A zero weight ignores that measure.
+// Scratch, if non-nil, holds the scaled image that is examined.
+type SmartCropOptions struct {
+	Cascade          *detect.Cascade
+	FaceWeight       float64
+	EdgeWeight       float64
+	SaturationWeight float64
+	Scratch          *Scratch
+}
+
+// DefaultSmartCropOptions are the smart cropping parameters used when none
+// are provided. Faces dominate, since cutting off a head is the worst
+// outcome for a thumbnail.
+var DefaultSmartCropOptions = SmartCropOptions{
+	FaceWeight:       4,
+	EdgeWeight:       1,
+	SaturationWeight: 0.5,
+}
+
+// SmartThumbnail scales and crops src so it fits in dst, like Thumbnail.
+// Rather than keeping the center of src, it keeps the crop window with the
+// highest score, according to the faces, edge energy and color saturation
+// it contains. Ties go to the window closest to the center.
+func SmartThumbnail(dst draw.Image, src image.Image, opt *SmartCropOptions) error {
+	if opt == nil {
+		opt = &DefaultSmartCropOptions
+	}
+
+	return thumbnail(dst, src, func(buf *image.RGBA, db image.Rectangle) image.Point {
+		var faces []image.Rectangle
+		if opt.Cascade != nil && opt.FaceWeight != 0 {
+			faces = opt.Cascade.Find(buf)
+		}
+		return smartCrop(buf, db, faces, opt)
+	}, opt.Scratch)
+}
+
+// smartCrop returns the top-left corner of the best scoring region of buf
+// with the size of db. Because buf is scaled to match db in one dimension,
+// the window only slides along the other.
+func smartCrop(buf *image.RGBA, db image.Rectangle, faces []image.Rectangle, opt *SmartCropOptions) image.Point {
+	b := buf.Bounds()
+	horizontal := b.Dx() > db.Dx()
+	n, size := b.Dy(), db.Dy()
+	if horizontal {
+		n, size = b.Dx(), db.Dx()
+	}
+	if n <= size {
+		return image.ZP
+	}
+
+	// profile[i] is the score of row i, or column i if horizontal.
+	profile := make([]float64, n)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			s := opt.EdgeWeight*edgeEnergy(buf, x, y) +
+				opt.SaturationWeight*saturation(buf, x, y)
+			if opt.FaceWeight != 0 {
+				for _, f := range faces {
+					if image.Pt(x, y).In(f) {
+						s += opt.FaceWeight
+						break
+					}
+				}
+			}
+			if horizontal {
+				profile[x-b.Min.X] += s
+			} else {
+				profile[y-b.Min.Y] += s
+			}
+		}
+	}
+
+	// Slide the window along the profile, keeping a running sum.
+	sum := 0.0
+	for i := 0; i < size; i++ {
+		sum += profile[i]
+	}
+	center := (n - size) / 2
+	best, bestSum := 0, sum
+	for i := 1; i+size <= n; i++ {
+		sum += profile[i+size-1] - profile[i-1]
+		if sum > bestSum || sum == bestSum && abs(i-center) < abs(best-center) {
+			best, bestSum = i, sum
+		}
+	}
+
+	if horizontal {
+		return image.Pt(best, 0)
+	}
+	return image.Pt(0, best)
+}
+
+// luma returns the luminance of the pixel at (x, y) in the range [0, 255].
+func luma(m *image.RGBA, x, y int) float64 {
+	off := (y-m.Rect.Min.Y)*m.Stride + (x-m.Rect.Min.X)*4
+	r, g, b := m.Pix[off+0], m.Pix[off+1], m.Pix[off+2]
+	return 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+}
+
+// edgeEnergy returns the gradient magnitude at (x, y) in the range [0, 1].
+func edgeEnergy(m *image.RGBA, x, y int) float64 {
+	b := m.Bounds()
+	c := luma(m, x, y)
+	var dx, dy float64
+	if x+1 < b.Max.X {
+		dx = luma(m, x+1, y) - c
+	}
+	if y+1 < b.Max.Y {
+		dy = luma(m, x, y+1) - c
+	}
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	return (dx + dy) / 510
+}
+
+// saturation returns the chroma of the pixel at (x, y) in the range [0, 1].
+func saturation(m *image.RGBA, x, y int) float64 {
+	off := (y-m.Rect.Min.Y)*m.Stride + (x-m.Rect.Min.X)*4
+	r, g, b := m.Pix[off+0], m.Pix[off+1], m.Pix[off+2]
+	lo, hi := r, r
+	if g < lo {
+		lo = g
+	}
+	if g > hi {
+		hi = g
+	}
+	if b < lo {
+		lo = b
+	}
+	if b > hi {
+		hi = b
+	}
+	return float64(hi-lo) / 255
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,94 @@
+// Copyright 2011 The Graphics-Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graphics
+
+import (
+	"github.com/BurntSushi/graphics-go/graphics/graphicstest"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	_ "image/png"
+)
+
+// checkerboard fills r in m with a one pixel black and white checkerboard.
+func checkerboard(m *image.RGBA, r image.Rectangle) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if (x+y)%2 == 0 {
+				m.SetRGBA(x, y, color.RGBA{0xff, 0xff, 0xff, 0xff})
+			} else {
+				m.SetRGBA(x, y, color.RGBA{0x00, 0x00, 0x00, 0xff})
+			}
+		}
+	}
+}
+
+func TestSmartCropEdges(t *testing.T) {
+	buf := image.NewRGBA(image.Rect(0, 0, 10, 30))
+	draw.Draw(buf, buf.Bounds(), image.White, image.ZP, draw.Src)
+	checkerboard(buf, image.Rect(0, 0, 10, 8))
+
+	pt := smartCrop(buf, image.Rect(0, 0, 10, 10), nil, &DefaultSmartCropOptions)
+	if want := image.Pt(0, 0); pt != want {
+		t.Errorf("got %v want %v", pt, want)
+	}
+}
+
+func TestSmartCropSaturation(t *testing.T) {
+	buf := image.NewRGBA(image.Rect(0, 0, 30, 10))
+	draw.Draw(buf, buf.Bounds(), image.White, image.ZP, draw.Src)
+	red := image.NewUniform(color.RGBA{0xff, 0x00, 0x00, 0xff})
+	draw.Draw(buf, image.Rect(22, 0, 30, 10), red, image.ZP, draw.Src)
+
+	opt := &SmartCropOptions{SaturationWeight: 1}
+	pt := smartCrop(buf, image.Rect(0, 0, 10, 10), nil, opt)
+	if want := image.Pt(20, 0); pt != want {
+		t.Errorf("got %v want %v", pt, want)
+	}
+}
+
+func TestSmartCropFaces(t *testing.T) {
+	buf := image.NewRGBA(image.Rect(0, 0, 10, 30))
+	draw.Draw(buf, buf.Bounds(), image.White, image.ZP, draw.Src)
+	checkerboard(buf, image.Rect(0, 0, 10, 8))
+
+	// A face outweighs the edges at the top of the image.
+	faces := []image.Rectangle{image.Rect(2, 20, 8, 28)}
+	pt := smartCrop(buf, image.Rect(0, 0, 10, 10), faces, &DefaultSmartCropOptions)
+	if want := image.Pt(0, 18); pt != want {
+		t.Errorf("got %v want %v", pt, want)
+	}
+}
+
+func TestSmartCropUniform(t *testing.T) {
+	buf := image.NewRGBA(image.Rect(0, 0, 10, 30))
+	draw.Draw(buf, buf.Bounds(), image.White, image.ZP, draw.Src)
+
+	// Without any features, fall back to the center.
+	pt := smartCrop(buf, image.Rect(0, 0, 10, 10), nil, &DefaultSmartCropOptions)
+	if want := image.Pt(0, 10); pt != want {
+		t.Errorf("got %v want %v", pt, want)
+	}
+}
+
+func TestSmartThumbnailGopher(t *testing.T) {
+	src, err := graphicstest.LoadImage("../testdata/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, 80, 80))
+	if err := SmartThumbnail(dst, src, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := SmartThumbnail(nil, src, nil); err == nil {
+		t.Error("nil dst: got nil error")
+	}
+	if err := SmartThumbnail(dst, nil, nil); err == nil {
+		t.Error("nil src: got nil error")
+	}
+}
@@ -5,37 +5,150 @@
 package graphics
 
 import (
+	"errors"
+	"github.com/BurntSushi/graphics-go/graphics/interp"
 	"image"
+	"image/color"
 	"image/draw"
 )
 
+// ThumbnailOptions are optional parameters to ThumbnailWith.
+// Scratch, if non-nil, holds any intermediate images so that they can be
+// reused between calls.
+type ThumbnailOptions struct {
+	Scratch *Scratch
+}
+
+// Scratch is a reusable buffer for intermediate images. The zero value is
+// ready to use. A Scratch must not be used by concurrent calls.
+type Scratch struct {
+	pix []uint8
+}
+
+// rgba returns a cleared image with bounds r, backed by the scratch buffer.
+// A nil Scratch allocates a new image.
+func (s *Scratch) rgba(r image.Rectangle) *image.RGBA {
+	if s == nil {
+		return image.NewRGBA(r)
+	}
+	n := 4 * r.Dx() * r.Dy()
+	if cap(s.pix) < n {
+		s.pix = make([]uint8, n)
+	}
+	pix := s.pix[:n]
+	for i := range pix {
+		pix[i] = 0
+	}
+	return &image.RGBA{Pix: pix, Stride: 4 * r.Dx(), Rect: r}
+}
+
 // Thumbnail scales and crops src so it fits in dst.
+// It keeps the center of src, and scales directly into dst.
 func Thumbnail(dst draw.Image, src image.Image) error {
-	// Scale down src in the dimension that is closer to dst.
+	return thumbnail(dst, src, nil, nil)
+}
+
+// ThumbnailWith is like Thumbnail, with optional parameters. A nil opt is
+// the same as calling Thumbnail.
+func ThumbnailWith(dst draw.Image, src image.Image, opt *ThumbnailOptions) error {
+	var scratch *Scratch
+	if opt != nil {
+		scratch = opt.Scratch
+	}
+	return thumbnail(dst, src, nil, scratch)
+}
+
+// cropFunc chooses the top-left corner of the region of buf, which is the
+// same size as db, to use as a thumbnail.
+type cropFunc func(buf *image.RGBA, db image.Rectangle) image.Point
+
+// thumbnail scales and crops src so it fits in dst. If crop is nil, the
+// crop region is centered and src is scaled straight into dst. Otherwise,
+// src is scaled into an intermediate image for crop to examine.
+func thumbnail(dst draw.Image, src image.Image, crop cropFunc, scratch *Scratch) error {
+	if dst == nil {
+		return errors.New("graphics: dst is nil")
+	}
+	if src == nil {
+		return errors.New("graphics: src is nil")
+	}
+
 	sb := src.Bounds()
 	db := dst.Bounds()
-	rx := float64(sb.Dx()) / float64(db.Dx())
-	ry := float64(sb.Dy()) / float64(db.Dy())
-	var b image.Rectangle
-	if rx < ry {
-		b = image.Rect(0, 0, db.Dx(), int(float64(sb.Dy())/rx))
-	} else {
-		b = image.Rect(0, 0, int(float64(sb.Dx())/ry), db.Dy())
-	}
-
-	buf := image.NewRGBA(b)
-	if err := Scale(buf, src); err != nil {
-		return err
-	}
-
-	// Crop.
-	// TODO(crawshaw): improve on center-alignment.
-	var pt image.Point
-	if rx < ry {
-		pt.Y = (b.Dy() - db.Dy()) / 2
-	} else {
-		pt.X = (b.Dx() - db.Dx()) / 2
-	}
-	draw.Draw(dst, db, buf, pt, draw.Src)
-	return nil
+	if db.Empty() || sb.Empty() {
+		return nil
+	}
+
+	b := image.Rectangle{image.ZP, thumbnailSize(sb, db)}
+
+	if crop != nil {
+		buf := scratch.rgba(b)
+		if err := Scale(buf, src); err != nil {
+			return err
+		}
+		draw.Draw(dst, db, buf, crop(buf, db), draw.Src)
+		return nil
+	}
+
+	// Only the centered crop region of the scaled image is computed.
+	// Its bounds are in the co-ordinate space of the scaled image, b.
+	pt := image.Pt((b.Dx()-db.Dx())/2, (b.Dy()-db.Dy())/2)
+	r := image.Rectangle{pt, pt.Add(db.Size())}
+	sx := float64(b.Dx()) / float64(sb.Dx())
+	sy := float64(b.Dy()) / float64(sb.Dy())
+	a := I.Translate(-float64(sb.Min.X), -float64(sb.Min.Y)).Scale(sx, sy)
+
+	// RGBA fast path, sharing the pixels of dst.
+	if dstRGBA, ok := dst.(*image.RGBA); ok {
+		return a.Transform(translateRGBA(dstRGBA, r.Min), src, interp.Bilinear)
+	}
+	if _, ok := src.(*image.RGBA); ok {
+		buf := scratch.rgba(r)
+		if err := a.Transform(buf, src, interp.Bilinear); err != nil {
+			return err
+		}
+		draw.Draw(dst, db, buf, r.Min, draw.Src)
+		return nil
+	}
+	return a.Transform(&translatedImage{dst, r.Min.Sub(db.Min)}, src, interp.Bilinear)
+}
+
+// thumbnailSize returns the size src is scaled to, before it is cropped to
+// fit in dst. It scales down src in the dimension that is closer to dst, and
+// rounds the other dimension up, so that neither is smaller than dst.
+func thumbnailSize(src, dst image.Rectangle) image.Point {
+	sw, sh, dw, dh := src.Dx(), src.Dy(), dst.Dx(), dst.Dy()
+	// sw/dw < sh/dh, without rounding.
+	if sw*dh < sh*dw {
+		return image.Pt(dw, (sh*dw+sw-1)/sw)
+	}
+	return image.Pt((sw*dh+sh-1)/sh, dh)
+}
+
+// translateRGBA returns an image sharing the pixels of m, with bounds moved
+// so they start at min.
+func translateRGBA(m *image.RGBA, min image.Point) *image.RGBA {
+	return &image.RGBA{
+		Pix:    m.Pix,
+		Stride: m.Stride,
+		Rect:   m.Rect.Add(min.Sub(m.Rect.Min)),
+	}
+}
+
+// translatedImage is m with its bounds moved by off.
+type translatedImage struct {
+	m   draw.Image
+	off image.Point
+}
+
+func (t *translatedImage) ColorModel() color.Model { return t.m.ColorModel() }
+
+func (t *translatedImage) Bounds() image.Rectangle { return t.m.Bounds().Add(t.off) }
+
+func (t *translatedImage) At(x, y int) color.Color {
+	return t.m.At(x-t.off.X, y-t.off.Y)
+}
+
+func (t *translatedImage) Set(x, y int, c color.Color) {
+	t.m.Set(x-t.off.X, y-t.off.Y, c)
 }
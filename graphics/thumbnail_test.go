@@ -7,6 +7,7 @@ package graphics
 import (
 	"github.com/BurntSushi/graphics-go/graphics/graphicstest"
 	"image"
+	"image/draw"
 	"testing"
 
 	_ "image/png"
@@ -51,3 +52,111 @@ func TestThumbnailLongGopher(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestThumbnailSubImage(t *testing.T) {
+	src, err := graphicstest.LoadImage("../testdata/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmp, err := graphicstest.LoadImage("../testdata/gopher-thumb-80x80.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Place src and dst away from the origin.
+	sb := src.Bounds()
+	off := image.Pt(13, 7)
+	srcOff := image.NewRGBA(sb.Add(off))
+	draw.Draw(srcOff, srcOff.Bounds(), src, sb.Min, draw.Src)
+	big := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	dst := big.SubImage(image.Rect(10, 20, 90, 100)).(*image.RGBA)
+
+	if err := Thumbnail(dst, srcOff); err != nil {
+		t.Fatal(err)
+	}
+	moved := &image.RGBA{Pix: dst.Pix, Stride: dst.Stride, Rect: cmp.Bounds()}
+	err = graphicstest.ImageWithinTolerance(moved, cmp, 0)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestThumbnailNonRGBA(t *testing.T) {
+	src, err := graphicstest.LoadImage("../testdata/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmp, err := graphicstest.LoadImage("../testdata/gopher-thumb-50x150.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scratch := new(Scratch)
+	for i := 0; i < 2; i++ {
+		dst := image.NewNRGBA(image.Rect(0, 0, 50, 150))
+		if err := ThumbnailWith(dst, src, &ThumbnailOptions{scratch}); err != nil {
+			t.Fatal(err)
+		}
+		err = graphicstest.ImageWithinTolerance(dst, cmp, 0x101)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestThumbnailEmpty(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	empty := image.NewRGBA(image.Rect(0, 0, 0, 0))
+	if err := Thumbnail(empty, src); err != nil {
+		t.Fatal(err)
+	}
+	if err := Thumbnail(src, empty); err != nil {
+		t.Fatal(err)
+	}
+	if err := Thumbnail(nil, src); err == nil {
+		t.Error("nil dst: got nil error")
+	}
+	if err := Thumbnail(src, nil); err == nil {
+		t.Error("nil src: got nil error")
+	}
+}
+
+func TestThumbnailSize(t *testing.T) {
+	// The scaled size is never smaller than dst, and one side matches it.
+	for sw := 1; sw <= 24; sw++ {
+		for sh := 1; sh <= 24; sh++ {
+			for dw := 1; dw <= sw; dw++ {
+				for dh := 1; dh <= sh; dh++ {
+					s := thumbnailSize(image.Rect(0, 0, sw, sh), image.Rect(0, 0, dw, dh))
+					if s.X < dw || s.Y < dh || (s.X != dw && s.Y != dh) {
+						t.Fatalf("%dx%d into %dx%d: got %v", sw, sh, dw, dh, s)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestThumbnailFill(t *testing.T) {
+	// Every pixel of dst is written, even when the scale does not divide
+	// the size of src.
+	src := image.NewRGBA(image.Rect(0, 0, 9, 9))
+	draw.Draw(src, src.Bounds(), image.White, image.ZP, draw.Src)
+	thumbs := []func(dst draw.Image, src image.Image) error{
+		Thumbnail,
+		func(dst draw.Image, src image.Image) error { return SmartThumbnail(dst, src, nil) },
+	}
+	for i, thumb := range thumbs {
+		dst := image.NewRGBA(image.Rect(0, 0, 7, 7))
+		if err := thumb(dst, src); err != nil {
+			t.Fatal(err)
+		}
+		for y := 0; y < 7; y++ {
+			for x := 0; x < 7; x++ {
+				if c := dst.RGBAAt(x, y); c.A != 0xff {
+					t.Fatalf("%d: (%d, %d) is %v", i, x, y, c)
+				}
+			}
+		}
+	}
+}